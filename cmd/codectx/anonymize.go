@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/harrison-m-freitas/codectx/internal/anon"
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+)
+
+// writeAnonymizeMap grava --anonymize-map: o mapa reverso fica só na máquina
+// local e nunca vai para o pacote.
+func writeAnonymizeMap(cfg cli.Config, log *logx.Logger) error {
+	if cfg.AnonymizeMap == "" {
+		return nil
+	}
+	d, err := anon.Load(cfg.Anonymize)
+	if err != nil {
+		return err
+	}
+	out, err := openOutputAtomic(cfg.AnonymizeMap, log)
+	if err != nil {
+		return fmt.Errorf("mapa de anonimização: %w", err)
+	}
+	defer out.Cleanup()
+	if err := d.WriteMap(out.Writer()); err != nil {
+		return fmt.Errorf("mapa de anonimização: %w", err)
+	}
+	if err := out.Commit(); err != nil {
+		return fmt.Errorf("mapa de anonimização: %w", err)
+	}
+	return nil
+}
+
+// runDeanonymize desfaz --anonymize nos arquivos de cfg.Targets (ou na
+// entrada padrão) e escreve o resultado na saída padrão.
+func runDeanonymize(cfg cli.Config, log *logx.Logger) int {
+	rv, err := anon.LoadMap(cfg.AnonymizeMap)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	inputs := cfg.Targets
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	for _, in := range inputs {
+		var data []byte
+		if in == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(in)
+		}
+		if err != nil {
+			log.Error("deanonymize: %v", err)
+			return 1
+		}
+		if _, err := io.WriteString(os.Stdout, rv.Text(string(data))); err != nil {
+			log.Error("deanonymize: %v", err)
+			return 1
+		}
+	}
+	return 0
+}
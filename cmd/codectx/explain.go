@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+// runExplain implementa `codectx explain`: escreve em stdout o rastro de
+// decisão de cada caminho e devolve o exit code.
+func runExplain(ctx context.Context, cfg cli.Config, log *logx.Logger) int {
+	exs := make([]*scan.Explanation, 0, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		ex, err := scan.Explain(ctx, cfg, t, log)
+		if err != nil {
+			log.Error("explain %s: %v", t, err)
+			return 1
+		}
+		exs = append(exs, ex)
+	}
+	if err := format.WriteExplain(os.Stdout, cfg, exs); err != nil {
+		log.Error("Falha ao escrever explain: %v", err)
+		return 1
+	}
+	return 0
+}
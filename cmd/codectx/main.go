@@ -10,6 +10,8 @@ import (
   "os/signal"
   "syscall"
   "path/filepath"
+  "sort"
+  "strings"
 
 	"github.com/harrison-m-freitas/codectx/internal/cli"
 	"github.com/harrison-m-freitas/codectx/internal/clipboard"
@@ -81,26 +83,20 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := cli.Validate(cfg); err != nil {
-		log.Error("%v", err)
-		os.Exit(1)
+	for _, f := range cfg.ConfigFiles {
+		log.Debug("Configuração aplicada: %s", f)
 	}
-
-	if cfg.Split {
-		log.Error("Split mode ainda não implementado nesta versão refatorada")
-		os.Exit(1)
+	if cfg.Profile != "" {
+		log.Debug("Perfil ativo: %s", cfg.Profile)
+	}
+	for _, f := range cfg.PolicyFiles {
+		log.Debug("Política aplicada: %s", f)
 	}
 
-	// Resolve clipboard backend cedo (para mensagem de sucesso)
-	cb := clipboard.New(log)
-
-	// Caminho de saída
-  out, err := openOutputAtomic(cfg.Output, log)
-	if err != nil {
-		log.Error("Falha ao criar arquivo de saída '%s': %v", cfg.Output, err)
+	if err := cli.Validate(cfg); err != nil {
+		log.Error("%v", err)
 		os.Exit(1)
 	}
-	defer out.Cleanup()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -115,6 +111,28 @@ func main() {
 
 	start := time.Now()
 
+	if cfg.Command == "explain" {
+		os.Exit(runExplain(ctx, cfg, log))
+	}
+	if cfg.Command == "deanonymize" {
+		os.Exit(runDeanonymize(cfg, log))
+	}
+
+	if cfg.Split {
+		os.Exit(runSplit(ctx, cfg, start, log))
+	}
+
+	// Resolve clipboard backend cedo (para mensagem de sucesso)
+	cb := clipboard.New(log)
+
+	// Caminho de saída
+  out, err := openOutputAtomic(cfg.Output, log)
+	if err != nil {
+		log.Error("Falha ao criar arquivo de saída '%s': %v", cfg.Output, err)
+		os.Exit(1)
+	}
+	defer out.Cleanup()
+
 	fileList, counters, err := scan.List(ctx, cfg, log)
   truncated := false
 	if err != nil {
@@ -128,11 +146,15 @@ func main() {
   }
 
 	if cfg.DryRun {
-    err := doDryRun(out.Writer(), fileList, counters, start, log);
+    err := doDryRun(out.Writer(), fileList, counters, cfg, start, log);
 		if err != nil {
 			log.Error("Falha no dry-run: %v", err)
 			os.Exit(1)
 		}
+		if err := writeSecretsReport(cfg, counters.SecretHits, nil, log); err != nil {
+			log.Error("%v", err)
+			os.Exit(1)
+		}
 		if !out.IsStdout() {
 			if err := out.Commit(); err != nil {
 				log.Error("Falha ao finalizar saída: %v", err)
@@ -145,22 +167,21 @@ func main() {
     return
 	}
 
-	// Cabeçalho do documento
-	if err := format.WriteDocHeader(out.Writer(), cfg); err != nil {
-		log.Error("Falha no cabeçalho do documento: %v", err)
+	metrics, err := writeBundle(ctx, out.Writer(), fileList, counters, cfg, log)
+	if err != nil {
+		log.Error("%v", err)
 		os.Exit(1)
 	}
-
-	// Processamento concorrente (determinístico na saída)
-	metrics, err := format.ProcessFiles(ctx, out.Writer(), fileList, cfg, log)
-	if err != nil {
+	if budgetExceeded(metrics, cfg, "", log) {
+		truncated = true
+	}
+	failed := len(metrics.Failed) > 0
+	if err := writeSecretsReport(cfg, counters.SecretHits, metrics.Redacted, log); err != nil {
 		log.Error("%v", err)
 		os.Exit(1)
 	}
-
-	// Rodapé com resumo adicional
-  if err := format.WriteSummaryFooter(out.Writer(), cfg, counters.SkippedBin, counters.SkippedSecret); err != nil {
-		log.Error("Falha no rodapé do documento: %v", err)
+	if err := writeAnonymizeMap(cfg, log); err != nil {
+		log.Error("%v", err)
 		os.Exit(1)
 	}
 
@@ -186,17 +207,134 @@ func main() {
   if elapsed > 0 {
     rate = float64(metrics.Files) / elapsed.Seconds()
   }
-  log.Info("Resumo: files=%d bytes=%d | binários_ignorados=%d | sensíveis_ignorados=%d | em %s | taxa=%.1f files/s",
-    metrics.Files, metrics.Bytes, counters.SkippedBin, counters.SkippedSecret, elapsed, rate)
+  log.Info("Resumo: files=%d bytes=%d tokens=%d (%s) | binários_ignorados=%d | sensíveis_ignorados=%d | em %s | taxa=%.1f files/s",
+    metrics.Files, metrics.Bytes, metrics.Tokens, metrics.Tokenizer, counters.SkippedBin, counters.SkippedSecret, elapsed, rate)
+	logSecretRules(counters, log)
 
+	if failed {
+		log.Warn("%d arquivo(s) com falha registrados no pacote (--keep-going).", len(metrics.Failed))
+		os.Exit(4)
+	}
   if truncated {
     os.Exit(3)
   }
 }
 
-func doDryRun(w io.Writer, files []scan.FileMeta, cn *scan.Counters, start time.Time, log *logx.Logger) error {
+// writeBundle escreve um documento completo: cabeçalho, arquivos e rodapé.
+func writeBundle(ctx context.Context, w io.Writer, files []scan.FileMeta, cn *scan.Counters, cfg cli.Config, log *logx.Logger) (*format.Metrics, error) {
+	// Cabeçalho do documento
+	info := format.DocInfo{
+		Changes: scan.ChangeSpec(cfg).Describe(),
+		Deleted: cn.Deleted,
+		Files:   files,
+		Skipped: cn.Skipped,
+	}
+	if cfg.Rev != "" {
+		rev, err := describeRev(cfg)
+		if err != nil {
+			return nil, err
+		}
+		info.Revision = rev
+	}
+
+	// Se o cabeçalho lista os arquivos (--tree, --toc, -F template), ele só
+	// pode ser escrito depois de saber quais entraram: o corpo vai para um
+	// arquivo temporário e é copiado após o cabeçalho.
+	body := w
+	var spool *os.File
+	if format.HeaderListsFiles(cfg) {
+		f, err := os.CreateTemp("", "codectx-*.tmp")
+		if err != nil {
+			return nil, fmt.Errorf("falha ao criar arquivo temporário: %w", err)
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		spool = f
+		body = spool
+	} else if err := format.WriteDocHeaderInfo(w, cfg, info); err != nil {
+		return nil, fmt.Errorf("falha no cabeçalho do documento: %w", err)
+	}
+
+	// Processamento concorrente (determinístico na saída)
+	// Com --max-tokens, o cabeçalho e o rodapé entram no orçamento
+	pre := format.FooterInfo{SkippedBin: cn.SkippedBin, SkippedSecret: cn.SkippedSecret}
+	metrics, err := format.ProcessFilesInfo(ctx, body, files, cfg, info, pre, log)
+	if err != nil {
+		return nil, err
+	}
+	for _, sk := range metrics.SecretSkips {
+		cn.Skip(sk.Path, sk.Decision)
+	}
+
+	if spool != nil {
+		info.Files, info.Failed, info.Skipped = metrics.Written, metrics.Failed, cn.Skipped
+		if err := format.WriteDocHeaderInfo(w, cfg, info); err != nil {
+			return nil, fmt.Errorf("falha no cabeçalho do documento: %w", err)
+		}
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(w, spool); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rodapé com resumo adicional
+	foot := format.FooterInfo{
+		SkippedBin:    cn.SkippedBin,
+		SkippedSecret: cn.SkippedSecret,
+		Tokens:        metrics.Tokens,
+		Tokenizer:     metrics.Tokenizer,
+		Budget:        metrics.Budget,
+		Omitted:       metrics.Omitted,
+		Cut:           metrics.Cut,
+		Failed:        metrics.Failed,
+		Redacted:      metrics.Redacted,
+		Files:         metrics.Written,
+	}
+	if err := format.WriteSummaryFooterInfo(w, cfg, foot); err != nil {
+		return nil, fmt.Errorf("falha no rodapé do documento: %w", err)
+	}
+	return metrics, nil
+}
+
+// budgetExceeded informa (e registra) se --max-tokens deixou arquivos de fora.
+func budgetExceeded(m *format.Metrics, cfg cli.Config, part string, log *logx.Logger) bool {
+	if m.Cut != "" {
+		log.Debug("Truncado para caber em --max-tokens: %s", m.Cut)
+	}
+	if len(m.Omitted) == 0 {
+		return false
+	}
+	where := ""
+	if part != "" {
+		where = " em '" + part + "'"
+	}
+	log.Warn("Orçamento atingido%s (--max-tokens=%d, %d usados). %d arquivo(s) omitido(s).", where, cfg.MaxTokens, m.Budget, len(m.Omitted))
+	return true
+}
+
+// describeRev resume --rev para o cabeçalho: "v1.2 (<sha>)" por repositório.
+func describeRev(cfg cli.Config) (string, error) {
+	trees, err := scan.Revisions(cfg)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(trees))
+	for _, t := range trees {
+		parts = append(parts, fmt.Sprintf("%s (%s)", t.Rev, t.Commit))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func doDryRun(w io.Writer, files []scan.FileMeta, cn *scan.Counters, cfg cli.Config, start time.Time, log *logx.Logger) error {
 	for _, fm := range files {
-		if _, err := fmt.Fprintf(w, "  %s (%d bytes)\n", util.ToSlash(fm.Path), fm.Size); err != nil {
+		if _, err := fmt.Fprintf(w, "  %s (%d bytes)\n", format.DisplayPath(fm.Path, cfg), fm.Size); err != nil {
+			return err
+		}
+	}
+	for _, d := range cn.Deleted {
+		if _, err := fmt.Fprintf(w, "  %s (removido)\n", format.DisplayPath(d, cfg)); err != nil {
 			return err
 		}
 	}
@@ -207,5 +345,24 @@ func doDryRun(w io.Writer, files []scan.FileMeta, cn *scan.Counters, start time.
 	}
 	log.Info("Resumo (DRY-RUN): files=%d bytes=%d | binários_ignorados=%d | sensíveis_ignorados=%d | em %s | taxa=%.1f files/s",
 		len(files), cn.TotalBytes, cn.SkippedBin, cn.SkippedSecret, elapsed, rate)
+	logSecretRules(cn, log)
 	return nil
 }
+
+// logSecretRules detalha, em modo verboso, as regras que marcaram arquivos
+// como sensíveis (ex.: "aws-access-key-id=1, dotenv=2").
+func logSecretRules(cn *scan.Counters, log *logx.Logger) {
+	if len(cn.SecretRules) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(cn.SecretRules))
+	for id := range cn.SecretRules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%s=%d", id, cn.SecretRules[id])
+	}
+	log.Debug("Sensíveis por regra: %s", strings.Join(parts, ", "))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
+)
+
+// writeSecretsReport grava --secrets-report: as ocorrências dos arquivos
+// omitidos e os segredos redigidos, em SARIF ou JSON conforme o nome.
+func writeSecretsReport(cfg cli.Config, skipped []secrets.Hit, redacted []format.FileRedactions, log *logx.Logger) error {
+	if cfg.SecretsReport == "" {
+		return nil
+	}
+	hits := append([]secrets.Hit(nil), skipped...)
+	for _, f := range redacted {
+		for _, it := range f.Items {
+			hits = append(hits, secrets.Hit{
+				Path:        f.Path,
+				Rule:        it.Rule,
+				Line:        it.Line,
+				Diff:        it.Diff,
+				Fingerprint: it.Fingerprint,
+				Action:      secrets.ActionRedacted,
+			})
+		}
+	}
+	rs, err := secrets.Load(cfg.SecretRules)
+	if err != nil {
+		return err
+	}
+	out, err := openOutputAtomic(cfg.SecretsReport, log)
+	if err != nil {
+		return fmt.Errorf("relatório de segredos: %w", err)
+	}
+	defer out.Cleanup()
+	kind := secrets.ReportFormat(cfg.SecretsReport)
+	if err := secrets.WriteReport(out.Writer(), kind, hits, rs); err != nil {
+		return fmt.Errorf("relatório de segredos: %w", err)
+	}
+	if err := out.Commit(); err != nil {
+		return fmt.Errorf("relatório de segredos: %w", err)
+	}
+	log.Info("Relatório de segredos (%s): %s, %d ocorrência(s)", kind, cfg.SecretsReport, len(hits))
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// runSplit gera um documento por subdiretório de primeiro nível de cada -p
+// dentro do diretório cfg.Output, mais um índice. Devolve o exit code.
+func runSplit(ctx context.Context, cfg cli.Config, start time.Time, log *logx.Logger) int {
+	parts, err := scan.ListSplit(ctx, cfg, log)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	if cfg.DryRun {
+		return splitDryRun(parts, cfg, start, log)
+	}
+
+	if err := util.EnsureDirAll(cfg.Output); err != nil {
+		log.Error("Falha ao criar diretório de saída '%s': %v", cfg.Output, err)
+		return 1
+	}
+
+	shown := make([]string, len(parts))
+	for i, part := range parts {
+		shown[i] = format.DisplayPath(part.Name, cfg)
+	}
+	files := format.SplitFiles(shown, cfg.Format)
+	entries := make([]format.SplitEntry, 0, len(parts))
+	truncated := false
+	failed := 0
+	var totalFiles, totalTokens, skippedBin, skippedSec int
+	var totalBytes int64
+	var hits []secrets.Hit
+	var redacted []format.FileRedactions
+	for i, part := range parts {
+		name := files[i]
+		partTrunc := errors.Is(part.Err, scan.ErrMaxFilesExceeded)
+		if partTrunc {
+			truncated = true
+			log.Warn("Limite atingido em '%s' (--max-files=%d). Processando %d arquivo(s).", part.Name, cfg.MaxFiles, len(part.Files))
+		}
+
+		metrics, err := writeSplitPart(ctx, filepath.Join(cfg.Output, name), part, cfg, log)
+		if err != nil {
+			log.Error("Falha na parte '%s': %v", part.Name, err)
+			return 1
+		}
+		if budgetExceeded(metrics, cfg, part.Name, log) {
+			partTrunc, truncated = true, true
+		}
+		failed += len(metrics.Failed)
+		hits = append(hits, part.Counters.SecretHits...)
+		redacted = append(redacted, metrics.Redacted...)
+		entries = append(entries, format.SplitEntry{
+			Name:          shown[i],
+			File:          name,
+			Files:         metrics.Files,
+			Bytes:         metrics.Bytes,
+			Tokens:        metrics.Tokens,
+			SkippedBin:    part.Counters.SkippedBin,
+			SkippedSecret: part.Counters.SkippedSecret,
+			Truncated:     partTrunc,
+		})
+		totalFiles += metrics.Files
+		totalBytes += metrics.Bytes
+		totalTokens += metrics.Tokens
+		skippedBin += part.Counters.SkippedBin
+		skippedSec += part.Counters.SkippedSecret
+	}
+
+	indexPath := filepath.Join(cfg.Output, format.SplitIndex+format.FileExt(cfg.Format))
+	idx, err := openOutputAtomic(indexPath, log)
+	if err != nil {
+		log.Error("Falha ao criar índice '%s': %v", indexPath, err)
+		return 1
+	}
+	defer idx.Cleanup()
+	if err := format.WriteSplitIndex(idx.Writer(), cfg, entries); err != nil {
+		log.Error("Falha ao escrever índice: %v", err)
+		return 1
+	}
+	if err := idx.Commit(); err != nil {
+		log.Error("Falha ao finalizar índice: %v", err)
+		return 1
+	}
+	if err := writeSecretsReport(cfg, hits, redacted, log); err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	if err := writeAnonymizeMap(cfg, log); err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	elapsed := time.Since(start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(totalFiles) / elapsed.Seconds()
+	}
+	log.Info("Resumo (split): partes=%d files=%d bytes=%d tokens=%d | binários_ignorados=%d | sensíveis_ignorados=%d | em %s | taxa=%.1f files/s",
+		len(entries), totalFiles, totalBytes, totalTokens, skippedBin, skippedSec, elapsed, rate)
+
+	if failed > 0 {
+		log.Warn("%d arquivo(s) com falha registrados nas partes (--keep-going).", failed)
+		return 4
+	}
+	if truncated {
+		return 3
+	}
+	return 0
+}
+
+func writeSplitPart(ctx context.Context, path string, part scan.Part, cfg cli.Config, log *logx.Logger) (*format.Metrics, error) {
+	out, err := openOutputAtomic(path, log)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Cleanup()
+	metrics, err := writeBundle(ctx, out.Writer(), part.Files, part.Counters, cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.Commit(); err != nil {
+		return nil, fmt.Errorf("falha ao finalizar saída: %w", err)
+	}
+	return metrics, nil
+}
+
+func splitDryRun(parts []scan.Part, cfg cli.Config, start time.Time, log *logx.Logger) int {
+	truncated := false
+	var hits []secrets.Hit
+	for _, part := range parts {
+		hits = append(hits, part.Counters.SecretHits...)
+		fmt.Fprintf(os.Stdout, "[%s]\n", format.DisplayPath(part.Name, cfg))
+		if err := doDryRun(os.Stdout, part.Files, part.Counters, cfg, start, log); err != nil {
+			log.Error("Falha no dry-run: %v", err)
+			return 1
+		}
+		if errors.Is(part.Err, scan.ErrMaxFilesExceeded) {
+			truncated = true
+		}
+	}
+	if err := writeSecretsReport(cfg, hits, nil, log); err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	if truncated {
+		return 3
+	}
+	return 0
+}
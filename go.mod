@@ -2,4 +2,10 @@ module github.com/harrison-m-freitas/codectx
 
 go 1.22.0
 
-require github.com/atotto/clipboard v0.1.4
+require (
+	github.com/atotto/clipboard v0.1.4
+	github.com/tiktoken-go/tokenizer v0.4.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/dlclark/regexp2 v1.11.5-0.20240806004527-5bbbed8ea10b // indirect
@@ -0,0 +1,216 @@
+// Package anon troca termos sensíveis (nomes de empresa, clientes, produtos)
+// por pseudônimos estáveis e desfaz a troca a partir do mapa reverso.
+//
+// O dicionário é um arquivo de texto com um termo por linha ("#" comenta). O
+// termo de posição i recebe o pseudônimo "anon" + i com largura fixa
+// ("anon001"): nenhum pseudônimo é prefixo de outro, então o texto volta ao
+// original mesmo quando o termo aparece colado a dígitos ("Acme2").
+package anon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Dict é um dicionário carregado.
+type Dict struct {
+	Source string
+	terms  []string          // na ordem do arquivo, sem repetição
+	pseudo map[string]string // termo em minúsculas -> pseudônimo
+	re     *regexp.Regexp
+}
+
+var loaded sync.Map // arquivo -> *Dict
+
+// Load lê o dicionário file; o resultado fica em cache, como o de
+// secrets.Load.
+func Load(file string) (*Dict, error) {
+	if v, ok := loaded.Load(file); ok {
+		return v.(*Dict), nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("dicionário de anonimização: %w", err)
+	}
+	defer f.Close()
+	d, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("dicionário de anonimização %s: %w", file, err)
+	}
+	d.Source = file
+	loaded.Store(file, d)
+	return d, nil
+}
+
+func parse(r io.Reader) (*Dict, error) {
+	d := &Dict{pseudo: map[string]string{}}
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		t := strings.TrimSpace(sc.Text())
+		if t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		if k := strings.ToLower(t); !seen[k] {
+			seen[k] = true
+			d.terms = append(d.terms, t)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(d.terms) == 0 {
+		return nil, fmt.Errorf("nenhum termo")
+	}
+	width := len(fmt.Sprint(len(d.terms)))
+	if width < 3 {
+		width = 3
+	}
+	for i, t := range d.terms {
+		d.pseudo[strings.ToLower(t)] = fmt.Sprintf("anon%0*d", width, i+1)
+	}
+	d.re = alternation(d.terms)
+	return d, nil
+}
+
+// alternation casa qualquer termo sem diferenciar caixa; os mais longos vêm
+// primeiro para que "Acme Cloud" vença "Acme".
+func alternation(terms []string) *regexp.Regexp {
+	sorted := append([]string(nil), terms...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	parts := make([]string, len(sorted))
+	for i, t := range sorted {
+		parts[i] = regexp.QuoteMeta(t)
+	}
+	return regexp.MustCompile("(?i)(?:" + strings.Join(parts, "|") + ")")
+}
+
+// Text devolve s com cada termo trocado pelo pseudônimo, na caixa do trecho
+// original: "ACME" -> "ANON001", "Acme" -> "Anon001", "acme" -> "anon001".
+// Receptor nil devolve s inalterado.
+func (d *Dict) Text(s string) string {
+	if d == nil || s == "" {
+		return s
+	}
+	return d.re.ReplaceAllStringFunc(s, func(m string) string {
+		return matchCase(m, d.lookup(m))
+	})
+}
+
+// Bytes é Text para []byte.
+func (d *Dict) Bytes(b []byte) []byte {
+	if d == nil || !d.re.Match(b) {
+		return b
+	}
+	return []byte(d.Text(string(b)))
+}
+
+func (d *Dict) lookup(m string) string {
+	if p, ok := d.pseudo[strings.ToLower(m)]; ok {
+		return p
+	}
+	for _, t := range d.terms { // dobras de caixa que ToLower não cobre
+		if strings.EqualFold(t, m) {
+			return d.pseudo[strings.ToLower(t)]
+		}
+	}
+	return m
+}
+
+// matchCase aplica a caixa de orig a repl (minúsculo).
+func matchCase(orig, repl string) string {
+	var upper, lower bool
+	for _, r := range orig {
+		upper = upper || unicode.IsUpper(r)
+		lower = lower || unicode.IsLower(r)
+	}
+	switch {
+	case upper && !lower:
+		return strings.ToUpper(repl)
+	case upper && unicode.IsUpper([]rune(orig)[0]):
+		return strings.ToUpper(repl[:1]) + repl[1:]
+	default:
+		return repl
+	}
+}
+
+// mapFile é o mapa reverso gravado com --anonymize-map.
+type mapFile struct {
+	Dictionary string            `json:"dictionary"`
+	Pseudonyms map[string]string `json:"pseudonyms"` // pseudônimo -> termo
+}
+
+// WriteMap grava o mapa reverso de d em w.
+func (d *Dict) WriteMap(w io.Writer) error {
+	mf := mapFile{Dictionary: d.Source, Pseudonyms: map[string]string{}}
+	for _, t := range d.terms {
+		mf.Pseudonyms[d.pseudo[strings.ToLower(t)]] = t
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mf)
+}
+
+// Reverse desfaz a anonimização a partir de um mapa gravado por WriteMap.
+type Reverse struct {
+	terms map[string]string // pseudônimo -> termo
+	re    *regexp.Regexp
+}
+
+// LoadMap lê o mapa reverso file.
+func LoadMap(file string) (*Reverse, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("mapa de anonimização: %w", err)
+	}
+	var mf mapFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&mf); err != nil {
+		return nil, fmt.Errorf("mapa de anonimização %s: %w", file, err)
+	}
+	if len(mf.Pseudonyms) == 0 {
+		return nil, fmt.Errorf("mapa de anonimização %s: nenhum pseudônimo", file)
+	}
+	rv := &Reverse{terms: map[string]string{}}
+	keys := make([]string, 0, len(mf.Pseudonyms))
+	for p, t := range mf.Pseudonyms {
+		rv.terms[strings.ToLower(p)] = t
+		keys = append(keys, p)
+	}
+	rv.re = alternation(keys)
+	return rv, nil
+}
+
+// Text devolve s com os pseudônimos trocados de volta pelos termos, na caixa
+// em que o pseudônimo aparece ("ANON001" -> "ACME").
+func (rv *Reverse) Text(s string) string {
+	return rv.re.ReplaceAllStringFunc(s, func(m string) string {
+		t, ok := rv.terms[strings.ToLower(m)]
+		if !ok {
+			return m
+		}
+		var upper, lower bool
+		for _, r := range m {
+			upper = upper || unicode.IsUpper(r)
+			lower = lower || unicode.IsLower(r)
+		}
+		switch {
+		case upper && !lower:
+			return strings.ToUpper(t)
+		case upper:
+			return t
+		default:
+			return strings.ToLower(t)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package anon_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/anon"
+)
+
+func TestAnonymizeRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "terms.txt")
+	_ = os.WriteFile(dict, []byte("# clientes\nAcme\nAcme Cloud\n\nGlobex\nacme\n"), 0o644)
+	d, err := anon.Load(dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := "AcmeClient usa Acme Cloud; ACME_KEY, acme2 e globex.\n"
+	got := d.Text(src)
+	want := "Anon001Client usa Anon002; ANON001_KEY, anon0012 e anon003.\n"
+	if got != want {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+
+	var m bytes.Buffer
+	if err := d.WriteMap(&m); err != nil {
+		t.Fatal(err)
+	}
+	mp := filepath.Join(dir, "map.json")
+	_ = os.WriteFile(mp, m.Bytes(), 0o644)
+	rv, err := anon.LoadMap(mp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := "AcmeClient usa Acme Cloud; ACME_KEY, acme2 e globex.\n"
+	if r := rv.Text(got); r != back {
+		t.Fatalf("reverso:\ngot  %q\nwant %q", r, back)
+	}
+}
+
+func TestLoadEmptyDictionary(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "terms.txt")
+	_ = os.WriteFile(p, []byte("# nada\n\n"), 0o644)
+	if _, err := anon.Load(p); err == nil {
+		t.Fatal("esperava erro para dicionário vazio")
+	}
+}
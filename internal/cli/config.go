@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Nomes aceitos para o arquivo de projeto, na ordem de preferência.
+var projectConfigNames = []string{".codectx.yaml", ".codectx.yml", ".codectx.json"}
+
+// Nomes aceitos para o arquivo do usuário (em <UserConfigDir>/codectx/).
+var userConfigNames = []string{"config.yaml", "config.yml", "config.json"}
+
+// fileConfig é o conteúdo de um arquivo de configuração. As chaves de
+// defaults/profiles são os nomes longos das flags sem "--" (ex.: "max-lines").
+type fileConfig struct {
+	Defaults map[string]any            `yaml:"defaults" json:"defaults"`
+	Profiles map[string]map[string]any `yaml:"profiles" json:"profiles"`
+
+	path string
+}
+
+// Chaves que só fazem sentido na linha de comando.
+var cliOnlyKeys = map[string]bool{
+	"help": true, "profile": true, "config": true, "no-config": true,
+}
+
+func loadConfigFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fc := &fileConfig{path: path}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(fc)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(fc)
+		if errors.Is(err, io.EOF) {
+			err = nil // arquivo vazio
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return fc, nil
+}
+
+// findProjectConfig sobe a partir de dir até a raiz procurando .codectx.*.
+func findProjectConfig(dir string) string {
+	for {
+		for _, n := range projectConfigNames {
+			p := filepath.Join(dir, n)
+			if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+				return p
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func findUserConfig() string {
+	base, err := os.UserConfigDir()
+	if err != nil || base == "" {
+		return ""
+	}
+	for _, n := range userConfigNames {
+		p := filepath.Join(base, "codectx", n)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			return p
+		}
+	}
+	return ""
+}
+
+// configFlags são as flags lidas antes do parse normal, pois decidem quais
+// arquivos de configuração entram.
+type configFlags struct {
+	profile  string
+	file     string
+	noConfig bool
+}
+
+func preScan(args []string) configFlags {
+	var cf configFlags
+	for i := 0; i < len(args); i++ {
+		key, val, hasKV := strings.Cut(args[i], "=")
+		switch key {
+		case "--profile", "--config":
+			if !hasKV {
+				if i+1 < len(args) {
+					val = args[i+1]
+				}
+				i++
+			}
+			if key == "--profile" {
+				cf.profile = val
+			} else {
+				cf.file = val
+			}
+		case "--no-config":
+			cf.noConfig = true
+		}
+	}
+	return cf
+}
+
+// loadConfigs devolve os arquivos de configuração em ordem de precedência
+// crescente: usuário primeiro, projeto depois.
+func loadConfigs(cf configFlags) ([]*fileConfig, error) {
+	if cf.noConfig {
+		return nil, nil
+	}
+	var paths []string
+	if p := findUserConfig(); p != "" {
+		paths = append(paths, p)
+	}
+	if cf.file != "" {
+		paths = append(paths, cf.file)
+	} else if wd, err := os.Getwd(); err == nil {
+		if p := findProjectConfig(wd); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	var out []*fileConfig
+	for _, p := range paths {
+		fc, err := loadConfigFile(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fc)
+	}
+	return out, nil
+}
+
+// applyConfigs aplica defaults e o perfil escolhido usando a mesma tabela de
+// opções da linha de comando, garantindo a mesma semântica de cada flag.
+func applyConfigs(files []*fileConfig, profile string, opts map[string]opt) error {
+	for _, fc := range files {
+		if err := applySection(fc.path, "defaults", fc.Defaults, opts); err != nil {
+			return err
+		}
+	}
+	if profile == "" {
+		return nil
+	}
+	found := false
+	for _, fc := range files {
+		sec, ok := fc.Profiles[profile]
+		if !ok {
+			continue
+		}
+		found = true
+		if err := applySection(fc.path, "profiles."+profile, sec, opts); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("perfil desconhecido: %s (disponíveis: %s)", profile, strings.Join(profileNames(files), ", "))
+	}
+	return nil
+}
+
+func profileNames(files []*fileConfig) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, fc := range files {
+		for n := range fc.Profiles {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"nenhum"}
+	}
+	return names
+}
+
+func applySection(path, section string, values map[string]any, opts map[string]opt) error {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // ordem determinística
+	for _, k := range keys {
+		spec, ok := opts["--"+k]
+		if !ok || cliOnlyKeys[k] {
+			return fmt.Errorf("config %s: %s: chave desconhecida %q", path, section, k)
+		}
+		if err := applyValue(spec, values[k]); err != nil {
+			return fmt.Errorf("config %s: %s.%s: %w", path, section, k, err)
+		}
+	}
+	return nil
+}
+
+func applyValue(spec opt, v any) error {
+	if !spec.needsValue {
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("esperava true/false, got %v", v)
+		}
+		spec.setB(b)
+		return nil
+	}
+	if list, ok := v.([]any); ok {
+		for _, item := range list {
+			s, err := scalarString(item)
+			if err != nil {
+				return err
+			}
+			spec.setV(s)
+		}
+		return nil
+	}
+	s, err := scalarString(v)
+	if err != nil {
+		return err
+	}
+	spec.setV(s)
+	return nil
+}
+
+func scalarString(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	default:
+		return "", fmt.Errorf("valor não suportado: %v", v)
+	}
+}
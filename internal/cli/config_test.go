@@ -0,0 +1,156 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+)
+
+// chdir muda o diretório de trabalho durante o teste e isola a config do usuário.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(old) })
+}
+
+const projectYAML = `
+defaults:
+  exclude: [vendor]
+  format: markdown
+profiles:
+  review:
+    max-lines: 400
+    order: mtime
+    index-only: true
+`
+
+func TestProjectConfigFoundWalkingUp(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".codectx.yaml"), []byte(projectYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "a", "b")
+	_ = os.MkdirAll(sub, 0o755)
+	chdir(t, sub)
+
+	cfg, _ := cli.Parse([]string{"-p", ".", "--profile", "review", "-l", "10"})
+	if err := cli.Validate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Format != "markdown" || cfg.Order != "mtime" || !cfg.IndexOnly {
+		t.Fatalf("defaults/perfil não aplicados: %+v", cfg)
+	}
+	if cfg.MaxLines != 10 {
+		t.Fatalf("flag explícita deveria vencer o perfil; MaxLines=%d", cfg.MaxLines)
+	}
+	if !strings.Contains(strings.Join(cfg.Excludes, ","), "vendor") || !strings.Contains(strings.Join(cfg.Excludes, ","), "node_modules") {
+		t.Fatalf("excludes da config deveriam somar aos padrões: %v", cfg.Excludes)
+	}
+	if len(cfg.ConfigFiles) != 1 {
+		t.Fatalf("esperava 1 arquivo de config, got %v", cfg.ConfigFiles)
+	}
+}
+
+// Booleanos explícitos (true ou false) do arquivo mais próximo vencem o do
+// usuário, nos dois sentidos.
+func TestConfigBoolCloserWins(t *testing.T) {
+	cases := []struct {
+		user, project string
+		want          bool
+	}{
+		{"true", "false", false},
+		{"false", "true", true},
+	}
+	for _, c := range cases {
+		root := t.TempDir()
+		chdir(t, root)
+		home := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", home)
+		_ = os.MkdirAll(filepath.Join(home, "codectx"), 0o755)
+		_ = os.WriteFile(filepath.Join(home, "codectx", "config.yaml"), []byte("defaults:\n  with-diff: "+c.user+"\n  keep-going: "+c.user+"\n"), 0o644)
+		_ = os.WriteFile(filepath.Join(root, ".codectx.yaml"), []byte("defaults:\n  with-diff: "+c.project+"\n  keep-going: "+c.project+"\n"), 0o644)
+
+		cfg, _ := cli.Parse([]string{"-p", "."})
+		if err := cli.Validate(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.ConfigFiles) != 2 {
+			t.Fatalf("esperava 2 arquivos de config, got %v", cfg.ConfigFiles)
+		}
+		if cfg.WithDiff != c.want || cfg.KeepGoing != c.want {
+			t.Fatalf("usuário=%s projeto=%s: got with-diff=%v keep-going=%v", c.user, c.project, cfg.WithDiff, cfg.KeepGoing)
+		}
+	}
+
+	// false no perfil desfaz o true dos defaults
+	root := t.TempDir()
+	chdir(t, root)
+	_ = os.WriteFile(filepath.Join(root, ".codectx.yaml"), []byte("defaults:\n  include-binaries: true\nprofiles:\n  safe:\n    include-binaries: false\n"), 0o644)
+	cfg, _ := cli.Parse([]string{"-p", ".", "--profile", "safe"})
+	if !cfg.BinarySkip {
+		t.Fatal("include-binaries: false no perfil deveria vencer os defaults")
+	}
+}
+
+func TestConfigUnknownProfileAndKey(t *testing.T) {
+	root := t.TempDir()
+	_ = os.WriteFile(filepath.Join(root, ".codectx.json"), []byte(`{"defaults":{"nope":1}}`), 0o644)
+	chdir(t, root)
+
+	cfg, _ := cli.Parse([]string{"-p", "."})
+	if err := cli.Validate(cfg); err == nil {
+		t.Fatal("chave desconhecida deveria falhar")
+	}
+
+	cfg, _ = cli.Parse([]string{"-p", ".", "--no-config", "--profile", "x"})
+	if err := cli.Validate(cfg); err == nil {
+		t.Fatal("perfil inexistente deveria falhar")
+	}
+}
+
+func TestPolicyForbidsFlagsAndAddsExcludes(t *testing.T) {
+	root := t.TempDir()
+	pol := "forbid: [danger-include-secrets, include-binaries]\nexclude: [\"customer-data/**\"]\n"
+	if err := os.WriteFile(filepath.Join(root, ".codectx-policy.yaml"), []byte(pol), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "svc")
+	_ = os.MkdirAll(sub, 0o755)
+	chdir(t, t.TempDir())
+
+	cfg, _ := cli.Parse([]string{"-p", sub, "-x", "!customer-data/**"})
+	if err := cli.Validate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := cli.PolicyExclude{Dir: root, Pattern: "customer-data/**"}
+	if len(cfg.PolicyExcludes) != 1 || cfg.PolicyExcludes[0] != want {
+		t.Fatalf("exclude da política deveria ficar ancorado em %s: %v", root, cfg.PolicyExcludes)
+	}
+	if !strings.HasPrefix(cfg.PolicyHash, "sha256:") || len(cfg.PolicyFiles) != 1 {
+		t.Fatalf("hash/arquivos da política: %q %v", cfg.PolicyHash, cfg.PolicyFiles)
+	}
+
+	cfg, _ = cli.Parse([]string{"-p", sub, "--no-config", "--include-binaries"})
+	err := cli.Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "proíbe --include-binaries") {
+		t.Fatalf("esperava recusa da política, got %v", err)
+	}
+
+	// definir a flag proibida pela configuração também é recusado
+	_ = os.WriteFile(filepath.Join(sub, ".codectx.yaml"), []byte("defaults:\n  danger-include-secrets: true\n"), 0o644)
+	cfg, _ = cli.Parse([]string{"-p", sub, "--config", filepath.Join(sub, ".codectx.yaml")})
+	if err := cli.Validate(cfg); err == nil || !strings.Contains(err.Error(), "configuração") {
+		t.Fatalf("esperava recusa da política para a configuração, got %v", err)
+	}
+}
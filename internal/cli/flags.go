@@ -6,6 +6,12 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/anon"
+	"github.com/harrison-m-freitas/codectx/internal/match"
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
+	"github.com/harrison-m-freitas/codectx/internal/tmpl"
+	"github.com/harrison-m-freitas/codectx/internal/tokens"
 )
 
 type Config struct {
@@ -18,19 +24,53 @@ type Config struct {
 	MaxLines      int
 	MaxCols       int
 	Output        string
-	Format        string // plain|markdown|fenced|json|ndjson
-	Order         string // path|ext|size|mtime
+	Format        string // plain|markdown|fenced|json|ndjson|xml|html|template
+	Order         string // path|ext|size|mtime|relevance
 	Split         bool
 	DryRun        bool
 	Quiet         bool
 	Verbose       bool
 	Clipboard     bool
 	SecretsStrict bool
+	Redact        bool // redigir segredos do conteúdo em vez de descartar o arquivo
+	SecretRules   string // arquivo de regras de segredo (estende/desliga/libera as embutidas)
+	SecretsReport string // relatório das ocorrências omitidas/redigidas (SARIF ou JSON)
+	Anonymize      string // dicionário de termos a trocar por pseudônimos
+	AnonymizePaths bool   // aplicar os pseudônimos também aos caminhos
+	AnonymizeMap   string // mapa reverso (pseudônimo -> termo), mantido localmente
 	BinarySkip    bool
 	IndexOnly     bool
+	Template      string // arquivo de template de -F template
+	Tree          bool // árvore dos arquivos selecionados no cabeçalho
+	TreeSkipped   bool // marcar na árvore os binários e sensíveis ignorados
+	TOC           bool // sumário com um item por arquivo no cabeçalho
   Jobs          int
   CaseInsensitive bool
   MaxFiles      int
+	KeepGoing     bool     // registrar falhas por arquivo e continuar
+	MaxMemory     int64    // teto de bytes na janela de reordenação (0 = sem teto)
+	MaxTokens     int      // orçamento global de tokens (0 = sem limite)
+	Tokenizer     string   // codificação BPE para contar tokens ("" = estimativa, ou tokens.Default com --max-tokens)
+	Query         string   // consulta para --order relevance
+	Pins          []string // padrões de arquivos prioritários no orçamento/ordem
+	Since         string // apenas arquivos alterados desde a ref
+	Staged        bool   // apenas arquivos no índice (staged)
+	DiffBase      string // apenas arquivos alterados desde o merge-base com a ref
+	Rev           string // ler árvore e conteúdo desta revisão git, sem checkout
+	WithDiff      bool   // anexar o diff unificado a cada arquivo
+	DiffOnly      bool   // emitir apenas os hunks do diff, sem o arquivo inteiro
+	DiffContext   int    // linhas de contexto dos hunks
+	Command       string   // subcomando ("" = gerar pacote | "explain" | "deanonymize")
+	Targets       []string // argumentos posicionais do subcomando
+	Profile       string   // perfil escolhido com --profile
+	ConfigFiles   []string // arquivos de configuração aplicados
+	PolicyFiles   []string // arquivos de política aplicados (sistema e repositório)
+	PolicyHash    string   // hash do conteúdo das políticas ("" sem política)
+	PolicyExcludes []PolicyExclude // excludes das políticas, avaliados à parte de Excludes
+
+	loadErr error             // erro ao carregar configuração; reportado por Validate
+	policy  *policy           // flags proibidas pela política
+	setBy   map[string]string // flag definida (nome longo) -> origem
 }
 
 func defaults() Config {
@@ -56,6 +96,8 @@ func defaults() Config {
     Jobs:          0, // 0 = auto (max(GOMAXPROCS, 4))
     CaseInsensitive: false,
     MaxFiles:      0,
+		MaxMemory:     256 << 20,
+		DiffContext:   3,
 	}
 }
 
@@ -103,10 +145,35 @@ func atoi64OrZero(s string) int64 {
   return n
 }
 
+// parseBytes aceita um número de bytes com sufixo opcional K, M ou G
+// (potências de 1024, com ou sem "B"). Valores inválidos viram -1 para que
+// Validate os rejeite.
+func parseBytes(s string) int64 {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n * mult
+}
+
 type opt struct {
 	needsValue bool
 	setV       func(string)
-	setB       func()
+	setB       func(on bool) // on=false só vem de arquivos de configuração
 }
 
 func appendCSV(dst *[]string, csv string) {
@@ -117,8 +184,13 @@ func Parse(args []string) (Config, bool) {
 	cfg := defaults()
 	showHelp := false
 
+	if len(args) > 0 && (args[0] == "explain" || args[0] == "deanonymize") {
+		cfg.Command = args[0]
+		args = args[1:]
+	}
+
   kv := func(set func(string)) opt { return opt{needsValue: true, setV: set} }
-  bf := func(set func()) opt { return opt{needsValue: false, setB: set} }
+  bf := func(set func(bool)) opt { return opt{needsValue: false, setB: set} }
   opts := map[string]opt{
     "-p": kv(func(v string) { cfg.Paths = append(cfg.Paths, v) }),
 		"--path": kv(func(v string) { cfg.Paths = append(cfg.Paths, v) }),
@@ -139,32 +211,102 @@ func Parse(args []string) (Config, bool) {
 		"--output": kv(func(v string) { cfg.Output = v }),
 		"-F": kv(func(v string) { cfg.Format = v }),
 		"--format": kv(func(v string) { cfg.Format = v }),
+		"--template": kv(func(v string) { cfg.Template = v }),
 		"-O": kv(func(v string) { cfg.Order = v }),
 		"--order": kv(func(v string) { cfg.Order = v }),
 		"-j": kv(func(v string) { cfg.Jobs = atoiOrZero(v) }),
 		"--jobs": kv(func(v string) { cfg.Jobs = atoiOrZero(v) }),
     "-N": kv(func(v string) { cfg.MaxFiles = atoiOrZero(v) }),
    "--max-files": kv(func(v string) { cfg.MaxFiles = atoiOrZero(v) }),
-    "-I": bf(func() { cfg.CaseInsensitive = true }),
-    "--ignore-case": bf(func() { cfg.CaseInsensitive = true }),
-    "--index-only": bf(func() { cfg.IndexOnly = true }),
-		"-c": bf(func() { cfg.Clipboard = true }),
-		"-C": bf(func() { cfg.Clipboard = true }),
-		"--clipboard": bf(func() { cfg.Clipboard = true }),
-		"-S": bf(func() { cfg.Split = true }),
-		"--split": bf(func() { cfg.Split = true }),
-		"-R": bf(func() { cfg.DryRun = true }),
-		"--dry-run": bf(func() { cfg.DryRun = true }),
-		"-q": bf(func() { cfg.Quiet = true }),
-		"--quiet": bf(func() { cfg.Quiet = true }),
-		"-v": bf(func() { cfg.Verbose = true }),
-		"--verbose": bf(func() { cfg.Verbose = true }),
-		"--danger-include-secrets": bf(func() { cfg.SecretsStrict = false }),
-		"--include-binaries": bf(func() { cfg.BinarySkip = false }),
-		"-h": bf(func() { showHelp = true }),
-		"--help": bf(func() { showHelp = true }),
+		"--max-memory": kv(func(v string) { cfg.MaxMemory = parseBytes(v) }),
+		"--max-tokens": kv(func(v string) { cfg.MaxTokens = atoiOrZero(v) }),
+		"--pin":        kv(func(v string) { appendCSV(&cfg.Pins, v) }),
+		"--tokenizer":  kv(func(v string) { cfg.Tokenizer = v }),
+		"--query":      kv(func(v string) { cfg.Query = v }),
+    "-I": bf(func(on bool) { cfg.CaseInsensitive = on }),
+    "--ignore-case": bf(func(on bool) { cfg.CaseInsensitive = on }),
+    "--index-only": bf(func(on bool) { cfg.IndexOnly = on }),
+		"--tree": bf(func(on bool) { cfg.Tree = on }),
+		"--tree-skipped": bf(func(on bool) {
+			cfg.TreeSkipped = on
+			if on {
+				cfg.Tree = true
+			}
+		}),
+		"--toc": bf(func(on bool) { cfg.TOC = on }),
+		"--keep-going": bf(func(on bool) { cfg.KeepGoing = on }),
+		"--since": kv(func(v string) { cfg.Since = v }),
+		"--staged": bf(func(on bool) { cfg.Staged = on }),
+		"--diff-base": kv(func(v string) { cfg.DiffBase = v }),
+		"--rev":       kv(func(v string) { cfg.Rev = v }),
+		"--with-diff": bf(func(on bool) { cfg.WithDiff = on }),
+		"--diff-only": bf(func(on bool) { cfg.DiffOnly = on }),
+		"--diff-context": kv(func(v string) { cfg.DiffContext = atoiOrZero(v) }),
+		"-c": bf(func(on bool) { cfg.Clipboard = on }),
+		"-C": bf(func(on bool) { cfg.Clipboard = on }),
+		"--clipboard": bf(func(on bool) { cfg.Clipboard = on }),
+		"-S": bf(func(on bool) { cfg.Split = on }),
+		"--split": bf(func(on bool) { cfg.Split = on }),
+		"-R": bf(func(on bool) { cfg.DryRun = on }),
+		"--dry-run": bf(func(on bool) { cfg.DryRun = on }),
+		"-q": bf(func(on bool) { cfg.Quiet = on }),
+		"--quiet": bf(func(on bool) { cfg.Quiet = on }),
+		"-v": bf(func(on bool) { cfg.Verbose = on }),
+		"--verbose": bf(func(on bool) { cfg.Verbose = on }),
+		"--danger-include-secrets": bf(func(on bool) { cfg.SecretsStrict = !on }),
+		"--redact": bf(func(on bool) { cfg.Redact = on }),
+		"--secret-rules": kv(func(v string) { cfg.SecretRules = v }),
+		"--secrets-report": kv(func(v string) { cfg.SecretsReport = v }),
+		"--anonymize": kv(func(v string) { cfg.Anonymize = v }),
+		"--anonymize-paths": bf(func(on bool) { cfg.AnonymizePaths = on }),
+		"--anonymize-map": kv(func(v string) { cfg.AnonymizeMap = v }),
+		"--include-binaries": bf(func(on bool) { cfg.BinarySkip = !on }),
+		"-h": bf(func(on bool) { showHelp = on }),
+		"--help": bf(func(on bool) { showHelp = on }),
+		// já tratadas por preScan; registradas para não virarem "opção desconhecida"
+		"--profile": kv(func(v string) { cfg.Profile = v }),
+		"--config": kv(func(string) {}),
+		"--no-config": bf(func(bool) {}),
   }
 
+	// Registra quais flags foram definidas e onde, para a política
+	cfg.setBy = map[string]string{}
+	origin := "configuração"
+	for k, o := range opts {
+		name := flagName(k)
+		if o.needsValue {
+			set := o.setV
+			o.setV = func(v string) { cfg.setBy[name] = origin; set(v) }
+		} else {
+			set := o.setB
+			o.setB = func(on bool) {
+				// false desfaz a flag: não conta para a política
+				if on {
+					cfg.setBy[name] = origin
+				} else {
+					delete(cfg.setBy, name)
+				}
+				set(on)
+			}
+		}
+		opts[k] = o
+	}
+
+	// Arquivos de configuração (usuário < projeto < perfil < flags explícitas)
+	cf := preScan(args)
+	files, err := loadConfigs(cf)
+	if err == nil {
+		err = applyConfigs(files, cf.profile, opts)
+	}
+	cfg.loadErr = err
+	for _, fc := range files {
+		cfg.ConfigFiles = append(cfg.ConfigFiles, fc.path)
+	}
+	// -p na linha de comando substitui os paths da configuração
+	cfgPaths := cfg.Paths
+	cfg.Paths = nil
+	origin = "linha de comando"
+
 	i := 0
 	for i < len(args) {
 		a := args[i]
@@ -186,7 +328,7 @@ func Parse(args []string) (Config, bool) {
 				shift(n)
 				continue
 			}
-			spec.setB()
+			spec.setB(true)
 			shift(1)
 			continue
 		}
@@ -196,38 +338,171 @@ func Parse(args []string) (Config, bool) {
         shift(1)
         break
       }
+      if cfg.Command != "" {
+        cfg.Targets = append(cfg.Targets, a)
+        shift(1)
+        continue
+      }
       fmt.Fprintf(os.Stderr, "Argumento inesperado: %s. Use -h para ajuda.\n", a)
       showHelp = true
       shift(1)
 	}
+	if len(cfg.Paths) == 0 {
+		cfg.Paths = cfgPaths
+	}
+	if cfg.Command == "explain" && len(cfg.Paths) == 0 {
+		cfg.Paths = []string{"."}
+	}
+
+	// Política: os excludes obrigatórios ficam fora de Excludes, relativos ao
+	// diretório de cada política, para que nenhum "!" nem -p os contorne
+	cfg.PolicyFiles = findPolicies(cfg.Paths)
+	if len(cfg.PolicyFiles) > 0 {
+		pol, hash, err := loadPolicies(cfg.PolicyFiles, opts)
+		if err != nil && cfg.loadErr == nil {
+			cfg.loadErr = err
+		}
+		if pol != nil {
+			cfg.policy, cfg.PolicyHash = pol, hash
+			cfg.PolicyExcludes = pol.exclude
+		}
+	}
 	return cfg, showHelp
 }
 
 func Validate(cfg Config) error {
+	if cfg.loadErr != nil {
+		return cfg.loadErr
+	}
+	if err := cfg.policy.check(cfg.setBy); err != nil {
+		return err
+	}
+	if cfg.Command == "deanonymize" {
+		if cfg.AnonymizeMap == "" {
+			return errors.New("deanonymize: informe o mapa com --anonymize-map FILE")
+		}
+		return nil
+	}
 	if len(cfg.Paths) == 0 {
 		return errors.New("nenhum path especificado. Use -p <dir>")
 	}
+	if cfg.Command == "explain" && len(cfg.Targets) == 0 {
+		return errors.New("explain: informe ao menos um caminho. Ex.: codectx explain -p . src/main.go")
+	}
 	switch cfg.Format {
-	case "plain", "markdown", "fenced", "json", "ndjson":
+	case "plain", "markdown", "fenced", "json", "ndjson", "xml", "html", "template":
 	default:
 		return fmt.Errorf("formato inválido: %s", cfg.Format)
 	}
+	if cfg.Format == "template" {
+		if cfg.Template == "" {
+			return errors.New("-F template requer --template arquivo.tmpl")
+		}
+		if _, err := tmpl.Load(cfg.Template); err != nil {
+			return err
+		}
+	} else if cfg.Template != "" {
+		return errors.New("--template só tem efeito com -F template")
+	}
 	switch cfg.Order {
-	case "path", "ext", "size", "mtime":
+	case "path", "ext", "size", "mtime", "relevance":
 	default:
 		return fmt.Errorf("ordenação inválida: %s", cfg.Order)
 	}
+	if cfg.Order == "relevance" && strings.TrimSpace(cfg.Query) == "" {
+		return errors.New("--order relevance requer --query \"termos\"")
+	}
+	if cfg.Query != "" && cfg.Order != "relevance" {
+		return errors.New("--query só tem efeito com --order relevance")
+	}
   if cfg.Jobs < 0 {
     return fmt.Errorf("--jobs deve ser >= 0")
   }
   if cfg.MaxFiles < 0 {
     return fmt.Errorf("--max-files deve ser >= 0")
   }
+	if cfg.MaxMemory < 0 {
+		return fmt.Errorf("--max-memory inválido (use N, NK, NM ou NG)")
+	}
+	if cfg.MaxTokens < 0 {
+		return fmt.Errorf("--max-tokens deve ser >= 0")
+	}
+	if !tokens.Valid(cfg.Tokenizer) {
+		return fmt.Errorf("--tokenizer inválido: %s (use %s, gpt-4o ou gpt-4)", cfg.Tokenizer, strings.Join(tokens.Encodings, "|"))
+	}
+	pats := append(append(append([]string{}, cfg.Excludes...), cfg.Includes...), cfg.Pins...)
+	for _, x := range cfg.PolicyExcludes {
+		pats = append(pats, x.Pattern)
+	}
+	for _, p := range pats {
+		if !match.IsGlob(p) {
+			continue
+		}
+		if _, err := match.Compile(p, cfg.CaseInsensitive); err != nil {
+			return fmt.Errorf("padrão inválido %q: %v", p, err)
+		}
+	}
+	if _, err := secrets.Load(cfg.SecretRules); err != nil {
+		return err
+	}
+	modes := 0
+	for _, on := range []bool{cfg.Since != "", cfg.Staged, cfg.DiffBase != ""} {
+		if on {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return errors.New("use apenas um entre --since, --staged e --diff-base")
+	}
+	if cfg.Rev != "" {
+		switch {
+		case modes > 0:
+			return errors.New("--rev não pode ser combinado com --since, --staged ou --diff-base")
+		case cfg.WithDiff || cfg.DiffOnly:
+			return errors.New("--rev não pode ser combinado com --with-diff ou --diff-only")
+		case cfg.Command == "explain":
+			return errors.New("explain avalia a árvore de trabalho; não use --rev")
+		}
+	}
+	if cfg.DiffContext < 0 {
+		return fmt.Errorf("--diff-context deve ser >= 0")
+	}
+	if cfg.Tree || cfg.TOC {
+		switch cfg.Format {
+		case "plain", "markdown", "fenced":
+		default:
+			return errors.New("--tree/--toc valem apenas para -F plain|markdown|fenced")
+		}
+	}
+	if cfg.DiffOnly && cfg.IndexOnly {
+		return errors.New("--diff-only e --index-only são mutuamente exclusivos")
+	}
+	if cfg.Anonymize != "" {
+		if _, err := anon.Load(cfg.Anonymize); err != nil {
+			return err
+		}
+	} else if cfg.AnonymizePaths || cfg.AnonymizeMap != "" {
+		return errors.New("--anonymize-paths e --anonymize-map requerem --anonymize FILE")
+	}
+	if cfg.AnonymizeMap != "" && cfg.AnonymizeMap == cfg.Output {
+		return errors.New("--anonymize-map precisa de um destino diferente de -o")
+	}
+	if cfg.SecretsReport != "" && cfg.SecretsReport == cfg.Output {
+		return errors.New("--secrets-report precisa de um destino diferente de -o")
+	}
+	if cfg.Split && cfg.Output == "-" {
+		return errors.New("--split grava em um diretório; use -o <dir> em vez de stdout")
+	}
+	if cfg.Split && cfg.Clipboard {
+		return errors.New("--split não suporta --clipboard (vários arquivos de saída)")
+	}
 	return nil
 }
 
 func Help() string {
 	return `USO: codectx [OPÇÕES]
+     codectx explain [OPÇÕES] CAMINHO...
+     codectx deanonymize --anonymize-map FILE [ARQUIVO...]
 
 DESCRIÇÃO:
 Coleta contexto de código de um ou mais diretórios, gerando arquivo(s) com
@@ -238,26 +513,133 @@ OPÇÕES:
 -d, --depth N          Profundidade máxima de recursão (0 = ilimitado)
 -e, --ext CSV          Extensões incluídas (ex: "js,ts,py")
 -x, --exclude PATTERN  Padrão de exclusão (pode repetir, CSV permitido)
--i, --include PATTERN  Padrão de inclusão (substring; CSV permitido)
+-i, --include PATTERN  Padrão de inclusão (pode repetir, CSV permitido)
 -m, --max-bytes N      Ignorar arquivos maiores que N bytes
     --max-cols N       Truncar cada linha para no máximo N colunas (sanitização)
 -l, --max-lines N      Limitar linhas por arquivo no contexto
 -o, --output FILE      Arquivo de saída, "-" para stdout (padrão: context.out)
 -c, --clipboard        Também copiar a saída final para a área de transferência
--S, --split            Gerar múltiplos arquivos por subdiretório (NÃO IMPLEMENTADO)
--F, --format TYPE      Formato: plain|markdown|fenced (padrão: plain)
--O, --order TYPE       Ordenação: path|ext|size|mtime (padrão: path)
+-S, --split            Um arquivo por subdiretório de primeiro nível de cada -p,
+                       gravados no diretório -o junto com um índice (_index)
+-F, --format TYPE      Formato: plain|markdown|fenced|json|ndjson|xml|html|
+                       template (padrão: plain)
+    --template FILE    Template (text/template) de -F template, com os blocos
+                       "header", "file" e "footer"; exemplos em
+                       examples/templates
+-O, --order TYPE       Ordenação: path|ext|size|mtime|relevance (padrão: path)
+    --query TEXT       Consulta para --order relevance: BM25 sobre termos de
+                       identificadores (camel/snake case), comentários e caminhos
 -j, --jobs N           Número de jobs paralelos (0 = auto, padrão: 0)
 -N, --max-files N      Número máximo de arquivos a processar (0 = ilimitado)
+    --max-memory N     Teto de memória para blocos prontos esperando a vez de
+                       serem escritos, em bytes ou com sufixo K/M/G; a saída
+                       é transmitida em ordem (0 = sem teto, padrão: 256M)
+    --max-tokens N     Orçamento global de tokens para os blocos de
+                       arquivo: entram os fixados e depois a ordem de --order;
+                       o último pode ser truncado; omitidos vão para o rodapé
+                       e o código de saída é 3
+    --pin PATTERN      Fixar arquivos no início da ordem (pode repetir, CSV)
+    --tokenizer ENC    Codificação BPE (offline) para contar tokens:
+                       cl100k_base|o200k_base|p50k_base|r50k_base, ou um
+                       modelo: gpt-4o|gpt-4. Sem ela os números são
+                       estimados (~4 bytes/token); --max-tokens usa
+                       cl100k_base por padrão
 -I, --ignore-case      Tornar filtros de inclusão/exclusão case-insensitive
     --index-only       Apenas gerar índice de arquivos, sem conteúdo
+    --tree             Árvore dos arquivos emitidos no cabeçalho (na ordem
+                       de --order)
+    --tree-skipped     Como --tree, marcando binários e sensíveis ignorados
+    --toc              Sumário no cabeçalho; em markdown, links para o "## path"
+                       de cada arquivo
+    --since REF        Apenas arquivos alterados desde REF (inclui não rastreados)
+    --staged           Apenas arquivos com alterações no índice (staged)
+    --diff-base REF    Apenas arquivos alterados desde o merge-base com REF
+    --rev REV          Ler arquivos e conteúdo da revisão REV (tag, branch, SHA)
+                       sem checkout; -p pode apontar para um repositório bare
+    --with-diff        Anexar o diff unificado de cada arquivo (bloco diff)
+    --diff-only        Emitir apenas os hunks do diff no lugar do arquivo inteiro
+    --diff-context N   Linhas de contexto dos hunks (padrão: 3)
+                       Sem --since/--staged/--diff-base o diff é contra HEAD
+    --keep-going       Registrar arquivos que falharem (sem permissão, removidos,
+                       linha longa demais) como bloco de erro ou campo "error"
+                       no JSON e continuar; o código de saída é 4
 -R, --dry-run          Apenas listar o que seria incluído
 -q, --quiet            Menos logs
 -v, --verbose          Mais logs
-    --danger-include-secrets Incluir arquivos sensíveis (não recomendado)
+    --danger-include-secrets Incluir arquivos sensíveis (não recomendado); desliga
+                       as regras de nome e a varredura de conteúdo
+    --redact           Manter arquivos com segredos no conteúdo, trocando cada
+                       segredo por [REDACTED:regra#n] (mesmo valor, mesmo n);
+                       hash e linhas continuam os do original; as redações vão
+                       para o rodapé e para o campo "redactions" do JSON
+    --secret-rules FILE Arquivo de regras de segredo (YAML/JSON) que acrescenta
+                       regras de nome ou de conteúdo, desliga regras embutidas
+                       e libera caminhos, ids ou fingerprints (allowlist)
+    --secrets-report FILE Gravar cada ocorrência de arquivo omitido ou segredo
+                       redigido (caminho, regra, linha, fingerprint, ação) em
+                       SARIF 2.1.0 (FILE terminado em .sarif ou .sarif.json)
+                       ou JSON (demais nomes)
+    --anonymize FILE   Trocar os termos do dicionário FILE (um por linha: nomes
+                       de empresa, clientes, produtos) por pseudônimos estáveis
+                       (anon001, Anon001, ANON001) no conteúdo e nos diffs
+    --anonymize-paths  Aplicar os mesmos pseudônimos aos caminhos (cabeçalhos,
+                       registros, rodapé, nomes das partes de --split)
+    --anonymize-map FILE Gravar o mapa reverso (pseudônimo -> termo) em FILE,
+                       para uso local com "codectx deanonymize"
     --include-binaries       Incluir arquivos binários (não recomendado)
+    --profile NOME     Aplicar o perfil NOME do arquivo de configuração
+    --config FILE      Usar FILE como configuração do projeto (sem busca)
+    --no-config        Ignorar arquivos de configuração
 -h, --help             Mostrar esta ajuda
 
+CONFIGURAÇÃO:
+Procura .codectx.yaml|.codectx.yml|.codectx.json subindo a partir do diretório
+atual, além de <config do usuário>/codectx/config.yaml|yml|json. Precedência:
+usuário < projeto < perfil (--profile) < flags explícitas. As chaves são os
+nomes longos das flags sem "--"; listas repetem a flag. Exemplo:
+
+  defaults:
+    exclude: [vendor, testdata]
+  profiles:
+    review:
+      format: markdown
+      max-lines: 400
+    llm-small:
+      ext: go,md
+      max-files: 50
+
+POLÍTICA:
+/etc/codectx/policy.yaml (no Windows, %ProgramData%\codectx\) e o primeiro
+.codectx-policy.yaml|.yml|.json subindo a partir de cada -p valem sempre,
+mesmo com --no-config. "forbid" lista flags que não podem ser usadas (nem pela
+configuração); "exclude" soma excludes relativos ao diretório do arquivo de
+política (na do sistema, a partir de qualquer diretório), que nem "!" nem um
+-p mais fundo desfazem. O hash das políticas vai para o cabeçalho do documento:
+
+  forbid: [danger-include-secrets, include-binaries]
+  exclude: ["customer-data/**"]
+
+PADRÕES (-x/-i):
+Com curingas (* ? [ ] **) ou "!" seguem o .gitignore, relativos à raiz -p:
+"*.pb.go", "**/testdata/**", "docs/*.md", "!keep/this.go". Sem "/" casam em
+qualquer nível; "/" inicial ou no meio ancora na raiz; "dir/" casa só
+diretórios. Avaliados em ordem: o último padrão que casar vence ("!" reinclui).
+Sem curingas mantêm a semântica simples: -x NOME casa um segmento do caminho
+(ou substring se contiver "/"); -i casa substring.
+
+ARQUIVOS DE IGNORE:
+Fora de um repositório git (ou sem o binário git) a varredura respeita os
+.gitignore de cada diretório abaixo de -p. Em ambos os modos, .codectxignore
+(mesma sintaxe) exclui arquivos só do pacote, sem mexer no .gitignore.
+
+SUBCOMANDOS:
+explain CAMINHO...     Mostra cada etapa do pipeline (git ls-files, arquivos de
+                       ignore, diretório podado, ext, exclude, secret, binary, include, size,
+                       secret-content, max-files) e o motivo de inclusão/descarte. Aceita as
+                       mesmas opções; -F json|ndjson gera JSON. -p padrão: "."
+deanonymize [ARQUIVO...] Desfaz --anonymize em ARQUIVO (ou na entrada padrão)
+                       com o mapa de --anonymize-map; escreve na saída padrão
+
 LOGS (variáveis de ambiente):
 LOG_LEVEL=0..4 (0=ERROR..4=TRACE), LOG_TS=0|1, LOG_COLOR=auto|always|never,
 LOG_JSON=0|1, LOG_FILE=/caminho/arquivo.log, NO_COLOR=1 desativa cor (auto).
@@ -272,6 +654,9 @@ EXEMPLOS:
 # Dry-run
 ./codectx -p lib -p bin -p docs --dry-run
 
+# Monorepo: um pacote por serviço em out/ (out/_index.md lista as partes)
+./codectx -p services --split -o out -F markdown
+
 # Para stdout com clipboard
 ./codectx -p src -p tests -o - -C -F fenced
 
@@ -279,6 +664,28 @@ EXEMPLOS:
 ./codectx -p . -F json --index-only > index.json
 ./codectx -p . -F ndjson --index-only | jq -c '.path'
 
+# Documentos XML (<document><source>…</source><document_content>…) para prompts
+./codectx -p src -F xml -o contexto.xml
+
+# Relatório HTML autocontido (árvore lateral, números de linha, realce)
+./codectx -p . --diff-base main --with-diff -F html -o revisao.html
+
+# Por que este arquivo não entrou no pacote?
+./codectx explain -p . -e go internal/secrets/manager.go
+./codectx explain -p . -F json cmd/codectx/main.go
+
+# Revisão de PR: só o que a branch alterou em relação à main
+./codectx -p . --diff-base main -F markdown
+./codectx -p . --diff-base main --diff-only --diff-context 10 -F fenced
+./codectx -p /srv/git/app.git --rev v2.0.0 -F markdown -o app-v2.md
+./codectx -p . -O relevance --query "refresh token rotation" --max-tokens 50000
+
+# Receita do projeto (.codectx.yaml) com perfil
+./codectx -p . --profile review
+
+# Padrões glob com negação (o último que casar vence)
+./codectx -p . -x "*.pb.go,**/testdata/**" -x "!**/testdata/golden.go"
+
 # Flags repetíveis/case-insensitive
 ./codectx -p . -e go -e "md,py" -x node_modules -x ".cache,.venv" -I
 `
@@ -1,6 +1,8 @@
 package cli_test
 
 import (
+	"os"
+	"strings"
 	"testing"
 	"github.com/harrison-m-freitas/codectx/internal/cli"
 )
@@ -31,3 +33,35 @@ func TestIgnoreCaseFlag(t *testing.T) {
 		t.Fatalf("esperava 'Node_Modules' nos excludes; got=%v", cfg.Excludes)
 	}
 }
+
+func TestMaxMemorySuffixes(t *testing.T) {
+	cases := map[string]int64{"0": 0, "4096": 4096, "64K": 64 << 10, "512MB": 512 << 20, "1g": 1 << 30}
+	for in, want := range cases {
+		cfg, _ := cli.Parse([]string{"--max-memory", in})
+		if cfg.MaxMemory != want {
+			t.Errorf("--max-memory %s: got %d, esperava %d", in, cfg.MaxMemory, want)
+		}
+	}
+	cfg, _ := cli.Parse([]string{"-p", ".", "--max-memory", "muito"})
+	if err := cli.Validate(cfg); err == nil || !strings.Contains(err.Error(), "--max-memory") {
+		t.Fatalf("esperava erro para --max-memory inválido, got %v", err)
+	}
+}
+
+func TestTemplateFlag(t *testing.T) {
+	dir := t.TempDir()
+	bad := dir + "/bad.tmpl"
+	_ = os.WriteFile(bad, []byte(`{{define "header"}}sem bloco file{{end}}`), 0o644)
+	cases := map[string][]string{
+		"requer --template": {"-p", ".", "-F", "template"},
+		"só tem efeito":     {"-p", ".", "--template", bad},
+		`defina o bloco`:    {"-p", ".", "-F", "template", "--template", bad},
+		"no such file":      {"-p", ".", "-F", "template", "--template", dir + "/nao-existe.tmpl"},
+	}
+	for want, args := range cases {
+		cfg, _ := cli.Parse(args)
+		if err := cli.Validate(cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: esperava erro com %q, got %v", args, want, err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Política da organização: flags proibidas e excludes obrigatórios. Ao
+// contrário da configuração, não há como desligá-la pela linha de comando
+// (--no-config e --config não a afetam). Vale a do sistema e a de cada
+// repositório que contém um -p, somadas:
+//
+//	forbid: [danger-include-secrets, include-binaries]
+//	exclude: ["customer-data/**"]
+var (
+	policyNames       = []string{".codectx-policy.yaml", ".codectx-policy.yml", ".codectx-policy.json"}
+	systemPolicyNames = []string{"policy.yaml", "policy.yml", "policy.json"}
+)
+
+type policyFile struct {
+	Forbid  []string `yaml:"forbid" json:"forbid"`
+	Exclude []string `yaml:"exclude" json:"exclude"`
+}
+
+// policy é a combinação dos arquivos de política encontrados.
+type policy struct {
+	forbid  map[string]string // flag (sem "--") -> arquivo que a proíbe
+	exclude []PolicyExclude
+}
+
+// PolicyExclude é um exclude obrigatório, ancorado no diretório do arquivo de
+// política que o declarou e não no -p: -p num subdiretório não o contorna.
+type PolicyExclude struct {
+	Dir     string // diretório do arquivo de política ("" na do sistema: casa a partir de qualquer diretório)
+	Pattern string
+}
+
+// shortFlags traduz as flags curtas para o nome longo usado em forbid.
+var shortFlags = map[string]string{
+	"-p": "path", "-d": "depth", "-e": "ext", "-x": "exclude", "-i": "include",
+	"-m": "max-bytes", "-l": "max-lines", "-o": "output", "-F": "format",
+	"-O": "order", "-j": "jobs", "-N": "max-files", "-I": "ignore-case",
+	"-c": "clipboard", "-C": "clipboard", "-S": "split", "-R": "dry-run",
+	"-q": "quiet", "-v": "verbose", "-h": "help",
+}
+
+// flagName devolve o nome longo (sem "--") da chave de opts.
+func flagName(key string) string {
+	if n, ok := shortFlags[key]; ok {
+		return n
+	}
+	return strings.TrimPrefix(key, "--")
+}
+
+func systemPolicyDir() string {
+	if runtime.GOOS == "windows" {
+		if pd := os.Getenv("ProgramData"); pd != "" {
+			return filepath.Join(pd, "codectx")
+		}
+		return ""
+	}
+	return "/etc/codectx"
+}
+
+// findPolicies devolve o arquivo de política do sistema e os de repositório,
+// procurados subindo a partir de cada raiz, sem repetição.
+func findPolicies(roots []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	if dir := systemPolicyDir(); dir != "" {
+		add(firstRegular(dir, systemPolicyNames))
+	}
+	for _, r := range roots {
+		dir, err := filepath.Abs(r)
+		if err != nil {
+			continue
+		}
+		if fi, err := os.Stat(dir); err == nil && !fi.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		for {
+			if p := firstRegular(dir, policyNames); p != "" {
+				add(p)
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return out
+}
+
+func firstRegular(dir string, names []string) string {
+	for _, n := range names {
+		p := filepath.Join(dir, n)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			return p
+		}
+	}
+	return ""
+}
+
+// loadPolicies lê os arquivos de política e devolve a combinação e o hash do
+// conteúdo (registrado no cabeçalho do documento).
+func loadPolicies(paths []string, opts map[string]opt) (*policy, string, error) {
+	pol := &policy{forbid: map[string]string{}}
+	h := sha256.New()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("política %s: %w", path, err)
+		}
+		var pf policyFile
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			err = dec.Decode(&pf)
+		} else {
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			err = dec.Decode(&pf)
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("política %s: %w", path, err)
+		}
+		for _, f := range pf.Forbid {
+			f = strings.TrimPrefix(strings.TrimSpace(f), "--")
+			if _, ok := opts["--"+f]; !ok || f == "help" {
+				return nil, "", fmt.Errorf("política %s: forbid: flag desconhecida %q", path, f)
+			}
+			if _, dup := pol.forbid[f]; !dup {
+				pol.forbid[f] = path
+			}
+		}
+		dir := filepath.Dir(path)
+		if !slices.Contains(policyNames, filepath.Base(path)) {
+			dir = ""
+		}
+		for _, x := range pf.Exclude {
+			if x = strings.TrimSpace(x); x == "" {
+				continue
+			}
+			if strings.HasPrefix(x, "!") {
+				return nil, "", fmt.Errorf("política %s: exclude: \"!\" não é permitido (%q)", path, x)
+			}
+			pol.exclude = append(pol.exclude, PolicyExclude{Dir: dir, Pattern: x})
+		}
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	return pol, "sha256:" + hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// check recusa as flags proibidas que foram definidas, na linha de comando ou
+// em um arquivo de configuração (set: flag -> origem).
+func (p *policy) check(set map[string]string) error {
+	if p == nil {
+		return nil
+	}
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if file, ok := p.forbid[n]; ok {
+			return fmt.Errorf("a política %s proíbe --%s (definida em %s)", file, n, set[n])
+		}
+	}
+	return nil
+}
@@ -1,62 +1,293 @@
 package filters
 
 import (
+	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 
 	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/match"
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
 	"github.com/harrison-m-freitas/codectx/internal/util"
 )
 
-var DefaultSecretExcludes = []string{
-	".env", ".env.", // .env e .env.*
-	".pem", ".key", ".p12", ".pfx", ".asc", ".gpg",
-	".git-credentials", ".npmrc", ".pypirc", ".s3cfg", ".boto",
-	".azure", ".aws", ".ssh",
-	".kdbx", ".keystore", ".jks",
+type Decision struct {
+	Include  bool
+	Reason   string
+	Rule     string            // etapas de segredo: id da regra que casou
+	Findings []secrets.Finding // secret-content: todas as ocorrências lidas
 }
 
-type Decision struct {
-	Include bool
-	Reason  string
+// Step é o resultado de uma etapa do filtro, usado por Trace (codectx explain).
+type Step struct {
+	Name   string `json:"step"`
+	Active bool   `json:"active"` // false quando a etapa está desligada pela config
+	Pass   bool   `json:"pass"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// subject é o caminho avaliado: como no disco, com "/" e relativo à raiz
+// (-p) que o contém — a forma usada pelos padrões glob.
+type subject struct {
+	path    string
+	slashed string
+	rel     string
+
+	blob bool   // conteúdo vem de uma revisão (--rev), não do disco
+	size int64  // tamanho do blob
+	data []byte // conteúdo do blob (binários e segredos)
+
+	read      bool // data já traz o conteúdo lido por quem o emite (DecideContent)
+	noContent bool // pula secret-content (DecideListed)
+
+	found *[]secrets.Finding // onde secret-content deixa as ocorrências
+}
+
+func newSubject(path string, cfg cli.Config) subject {
+	ps := util.ToSlash(path)
+	return subject{path: path, slashed: ps, rel: relToRoot(ps, cfg.Paths)}
+}
+
+// check avalia uma etapa; active=false significa que ela não se aplica.
+type check struct {
+	name      string
+	needsFile bool // lê o arquivo (não se aplica a caminhos removidos)
+	run       func(s subject, cfg cli.Config) (active, pass bool, detail string)
+	rule      bool // detail é o id da regra que reprovou (etapas de segredo)
+}
+
+// As etapas em ordem; Decide para na primeira reprovação, Trace avalia todas.
+var checks = []check{
+	// 1) Extensão (CSV permitido)
+	{"ext", false, func(s subject, cfg cli.Config) (bool, bool, string) {
+		if cfg.ExtCSV == "" {
+			return false, true, ""
+		}
+		return true, hasAllowedExt(s.slashed, cfg.ExtCSV), "permitidas: " + cfg.ExtCSV
+	}, false},
+	// 2) Excludes (substring ou glob, CSV permitido; o último que casar vence)
+	{"exclude", false, func(s subject, cfg cli.Config) (bool, bool, string) {
+		if len(cfg.Excludes) == 0 && len(cfg.PolicyExcludes) == 0 {
+			return false, true, ""
+		}
+		if p := matchPolicy(s.slashed, false, cfg); p != "" {
+			return true, false, "política: " + p
+		}
+		if p := matchExclude(s, cfg.Excludes, cfg.CaseInsensitive); p != "" {
+			return true, false, "padrão: " + p
+		}
+		return true, true, ""
+	}, false},
+	// 3) Segredos
+	{"secret", false, func(s subject, cfg cli.Config) (bool, bool, string) {
+		if !cfg.SecretsStrict {
+			return false, true, ""
+		}
+		if rule := secretRules(cfg).MatchPath(s.rel, s.slashed); rule != "" {
+			return true, false, rule
+		}
+		return true, true, ""
+	}, true},
+	// 4) Binários (NUL)
+	{"binary", true, func(s subject, cfg cli.Config) (bool, bool, string) {
+		if !cfg.BinarySkip {
+			return false, true, ""
+		}
+		if s.blob {
+			if util.HasNUL(s.data) {
+				return true, false, "byte NUL nos primeiros 4 KiB"
+			}
+			return true, true, ""
+		}
+		bin, err := util.IsBinary(s.path)
+		if err != nil {
+			return true, true, "não foi possível ler: " + err.Error()
+		}
+		if bin {
+			return true, false, "byte NUL nos primeiros 4 KiB"
+		}
+		return true, true, ""
+	}, false},
+	// 5) Includes (caminho completo)
+	{"include", false, func(s subject, cfg cli.Config) (bool, bool, string) {
+		if len(cfg.Includes) == 0 {
+			return false, true, ""
+		}
+		if isIncludedPath(s, cfg.Includes, cfg.CaseInsensitive) {
+			return true, true, ""
+		}
+		return true, false, "nenhum padrão casou: " + strings.Join(cfg.Includes, ",")
+	}, false},
+	// 6) Tamanho
+	{"size", true, func(s subject, cfg cli.Config) (bool, bool, string) {
+		if cfg.MaxBytes <= 0 {
+			return false, true, ""
+		}
+		sz := s.size
+		if !s.blob {
+			sz = util.FileSize(s.path)
+		}
+		return true, sz <= cfg.MaxBytes, fmt.Sprintf("%d bytes (máx. %d)", sz, cfg.MaxBytes)
+	}, false},
+	// 7) Segredos no conteúdo (por último: é a etapa mais cara)
+	{"secret-content", true, func(s subject, cfg cli.Config) (bool, bool, string) {
+		if !cfg.SecretsStrict {
+			return false, true, ""
+		}
+		if cfg.Redact {
+			return false, true, "desligada: segredos no conteúdo são redigidos (--redact)"
+		}
+		if s.noContent {
+			return false, true, ""
+		}
+		data := s.data
+		if !s.blob && !s.read {
+			var err error
+			if data, err = readPrefix(s.path, MaxScanBytes); err != nil {
+				return false, true, ""
+			}
+		}
+		if fs := secretRules(cfg).Scan(s.rel, data); len(fs) > 0 {
+			if s.found != nil {
+				*s.found = fs
+			}
+			return true, false, fs[0].Rule + " (fingerprint " + fs[0].Fingerprint + ")"
+		}
+		return true, true, ""
+	}, true},
+}
+
+// MaxScanBytes limita quanto de cada arquivo a etapa secret-content lê.
+const MaxScanBytes = 8 << 20
+
+func readPrefix(path string, n int64) ([]byte, error) {
+	f, err := util.OpenRead(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, n))
 }
 
 func Decide(path string, cfg cli.Config) Decision {
-	pathSlashed := util.ToSlash(path)
+	return decide(newSubject(path, cfg), cfg)
+}
+
+// DecideListed é como Decide, sem a etapa secret-content: a listagem que
+// antecede a emissão usa DecideListed e quem lê o arquivo para emiti-lo
+// aplica DecideContent aos mesmos bytes, para que cada arquivo seja lido uma
+// só vez.
+func DecideListed(path string, cfg cli.Config) Decision {
+	s := newSubject(path, cfg)
+	s.noContent = true
+	return decide(s, cfg)
+}
 
-  // 1) Extensão (CSV permitido)
-  if cfg.ExtCSV != "" && !hasAllowedExt(pathSlashed, cfg.ExtCSV) {
-		return Decision{false, "ext"}
+// DecideContent aplica só a etapa secret-content a data, o início (até
+// MaxScanBytes) do conteúdo de path já lido.
+func DecideContent(path string, data []byte, cfg cli.Config) Decision {
+	if len(data) > MaxScanBytes {
+		data = data[:MaxScanBytes]
 	}
+	s := newSubject(path, cfg)
+	s.read, s.data = true, data
+	var found []secrets.Finding
+	s.found = &found
+	c := checks[len(checks)-1]
+	if active, pass, detail := c.run(s, cfg); active && !pass {
+		d := c.reject(detail)
+		d.Findings = found
+		return d
+	}
+	return Decision{Include: true, Reason: "ok"}
+}
+
+// DecideBlob é como Decide para um arquivo lido de uma revisão (--rev): o
+// tamanho e o conteúdo vêm do blob em vez do disco.
+func DecideBlob(path string, size int64, data []byte, cfg cli.Config) Decision {
+	s := newSubject(path, cfg)
+	s.blob, s.size, s.data = true, size, data
+	return decide(s, cfg)
+}
 
-  // 2) Excludes (substring, CSV permitido)
-  if isExcludedPath(pathSlashed, cfg.Excludes, cfg.CaseInsensitive) {
-		return Decision{false, "exclude"}
+func decide(s subject, cfg cli.Config) Decision {
+	var found []secrets.Finding
+	s.found = &found
+	for _, c := range checks {
+		if active, pass, detail := c.run(s, cfg); active && !pass {
+			d := c.reject(detail)
+			d.Findings = found
+			return d
+		}
 	}
+	return Decision{Include: true, Reason: "ok"}
+}
 
-	// 3) Segredos
-	if cfg.SecretsStrict && isSensitiveFile(pathSlashed) {
-		return Decision{false, "secret"}
+// reject monta a decisão de uma etapa reprovada.
+func (c check) reject(detail string) Decision {
+	d := Decision{Reason: c.name}
+	if c.rule {
+		d.Rule, _, _ = strings.Cut(detail, " ")
 	}
+	return d
+}
 
-	// 4) Binários (NUL)
-	if cfg.BinarySkip {
-		if bin, _ := util.IsBinary(path); bin {
-			return Decision{false, "binary"}
-		}
+// secretRules devolve as regras de segredo da configuração (--secret-rules).
+// Um arquivo inválido é rejeitado por cli.Validate antes de qualquer
+// decisão; aqui ele cai nas regras embutidas.
+func secretRules(cfg cli.Config) *secrets.RuleSet {
+	rs, err := secrets.Load(cfg.SecretRules)
+	if err != nil {
+		return secrets.Default()
 	}
+	return rs
+}
 
-  // 5) Includes (caminho completo)
-  if len(cfg.Includes) > 0 && !isIncludedPath(pathSlashed, cfg.Includes, cfg.CaseInsensitive) {
-		return Decision{false, "include"}
+// Findings devolve os segredos em data, lido de path, pelas regras e pelo
+// allowlist de cfg; usado por --redact.
+func Findings(path string, data []byte, cfg cli.Config) []secrets.Finding {
+	s := newSubject(path, cfg)
+	return secretRules(cfg).Scan(s.rel, data)
+}
+
+// DecidePath aplica apenas as etapas que dependem do caminho (ext, exclude,
+// secret, include); usado para arquivos que não existem mais no disco.
+func DecidePath(path string, cfg cli.Config) Decision {
+	s := newSubject(path, cfg)
+	for _, c := range checks {
+		if c.needsFile {
+			continue
+		}
+		if active, pass, detail := c.run(s, cfg); active && !pass {
+			return c.reject(detail)
+		}
 	}
+	return Decision{Include: true, Reason: "ok"}
+}
 
-	// 6) Tamanho
-	if cfg.MaxBytes > 0 && util.FileSize(path) > cfg.MaxBytes {
-		return Decision{false, "size"}
+// Trace avalia todas as etapas de Decide (sem parar na primeira reprovação)
+// e devolve também a decisão final, idêntica à de Decide.
+func Trace(path string, cfg cli.Config) ([]Step, Decision) {
+	s := newSubject(path, cfg)
+	steps := make([]Step, 0, len(checks))
+	dec := Decision{Include: true, Reason: "ok"}
+	for _, c := range checks {
+		active, pass, detail := c.run(s, cfg)
+		if active && !pass && dec.Include {
+			dec = c.reject(detail)
+		}
+		if c.rule && active && !pass {
+			detail = "regra: " + detail
+		}
+		steps = append(steps, Step{Name: c.name, Active: active, Pass: pass || !active, Detail: detail})
 	}
+	return steps, dec
+}
 
-	return Decision{true, "ok"}
+// Pinned informa se path casa com algum --pin (mesma semântica de --include).
+func Pinned(path string, cfg cli.Config) bool {
+	return len(cfg.Pins) > 0 && isIncludedPath(newSubject(path, cfg), cfg.Pins, cfg.CaseInsensitive)
 }
 
 func splitCSV(s string) []string {
@@ -84,85 +315,178 @@ func hasAllowedExt(pathSlashed string, csv string) bool {
 	return false
 }
 
-func isExcludedPath(pathSlashed string, excludes []string, insensitive bool) bool {
-	if len(excludes) == 0 {
-		return false
+// relToRoot devolve ps relativo à primeira raiz de roots que o contém; sem
+// raiz conhecida, devolve ps inalterado (padrões não ancorados ainda casam).
+func relToRoot(ps string, roots []string) string {
+	for _, r := range roots {
+		abs, err := util.Abs(r)
+		if err != nil {
+			continue
+		}
+		root := strings.TrimSuffix(util.ToSlash(abs), "/") + "/"
+		if strings.HasPrefix(ps, root) {
+			return ps[len(root):]
+		}
 	}
-	ps := pathSlashed // alias
-  if insensitive {
-    ps = strings.ToLower(pathSlashed)
-  }
+	return ps
+}
+
+// matchExclude devolve o padrão decisivo quando o caminho está excluído ("" se
+// não está). Padrões glob (match.IsGlob) seguem o .gitignore e "!" reinclui;
+// os demais mantêm a semântica antiga: segmento exato ou, com "/", substring.
+// O último padrão que casar vence.
+func matchExclude(s subject, excludes []string, insensitive bool) string {
+	decisive := ""
 	for _, p := range excludes {
 		p = strings.TrimSpace(p)
 		if p == "" {
 			continue
 		}
-    needle := p
-    if insensitive {
-      needle = strings.ToLower(needle)
-    }
-		if strings.ContainsAny(needle, "*/?") || strings.Contains(needle, "/") {
-			if strings.Contains(ps, needle) {
-				return true
+		if match.IsGlob(p) {
+			pat, err := match.Compile(p, insensitive)
+			if err != nil || !pat.Match(s.rel, false) {
+				continue
+			}
+			decisive = p
+			if pat.Negate {
+				decisive = ""
 			}
 			continue
 		}
-		needle = "/" + needle + "/"
-		if strings.Contains(ps+"/", needle) || strings.HasSuffix(ps, "/"+needle) {
-			return true
+		if legacyExcluded(s.slashed, p, insensitive) {
+			decisive = p
 		}
 	}
-	return false
+	return decisive
 }
 
-func isIncludedPath(pathSlashed string, includes []string, insensitive bool) bool {
-  ps := pathSlashed
-  if insensitive {
-    ps = strings.ToLower(ps)
-  }
-  for _, inc := range includes {
-    if inc == "" {
-       continue
-     }
-    needle := inc
-    if insensitive {
-      needle = strings.ToLower(needle)
-    }
-    if strings.Contains(ps, needle) {
-       return true
-     }
-   }
-   return false
-}
-
-func isSensitiveFile(path string) bool {
-	if strings.Contains(path, "/.ssh/") ||
-		strings.Contains(path, "/.aws/") ||
-		strings.Contains(path, "/.azure/") ||
-		strings.Contains(path, "/.gnupg/") ||
-		strings.Contains(path, "/.secrets/") {
-		return true
-	}
-	base := util.Base(path)
-	if base == ".env" || strings.HasPrefix(base, ".env.") {
-		return true
-	}
-	lowBase := strings.ToLower(base)
-	for _, suf := range []string{".pem", ".key", ".p12", ".pfx", ".asc", ".gpg", ".kdbx", ".keystore", ".jks"} {
-		if strings.HasSuffix(lowBase, suf) {
-			return true
+func legacyExcluded(ps, p string, insensitive bool) bool {
+	needle := p
+	if insensitive {
+		ps = strings.ToLower(ps)
+		needle = strings.ToLower(needle)
+	}
+	if strings.Contains(needle, "/") {
+		return strings.Contains(ps, needle)
+	}
+	needle = "/" + needle + "/"
+	return strings.Contains(ps+"/", needle) || strings.HasSuffix(ps, "/"+needle)
+}
+
+// isIncludedPath aplica os includes com a mesma regra de matchExclude: glob
+// ou substring, último que casar vence.
+func isIncludedPath(s subject, includes []string, insensitive bool) bool {
+	included := false
+	for _, inc := range includes {
+		inc = strings.TrimSpace(inc)
+		if inc == "" {
+			continue
+		}
+		if match.IsGlob(inc) {
+			pat, err := match.Compile(inc, insensitive)
+			if err == nil && pat.Match(s.rel, false) {
+				included = !pat.Negate
+			}
+			continue
+		}
+		ps, needle := s.slashed, inc
+		if insensitive {
+			ps, needle = strings.ToLower(ps), strings.ToLower(needle)
+		}
+		if strings.Contains(ps, needle) {
+			included = true
 		}
 	}
-	if base == ".git-credentials" || base == ".npmrc" || base == ".pypirc" || base == "composer.auth.json" {
-		return true
+	return included
+}
+
+// matchPolicy devolve o exclude de política que casa com ps ("" se nenhum).
+// Cada padrão é avaliado contra o caminho relativo ao diretório da política
+// que o declarou; os da política do sistema, contra o caminho a partir de
+// cada diretório ancestral. Não há "!" que os desfaça.
+func matchPolicy(ps string, dir bool, cfg cli.Config) string {
+	for _, x := range cfg.PolicyExcludes {
+		glob := match.IsGlob(x.Pattern)
+		pat, err := match.Compile(x.Pattern, cfg.CaseInsensitive)
+		if glob && err != nil {
+			continue
+		}
+		for _, rel := range policyRels(ps, x.Dir) {
+			if glob && pat.Match(rel, dir) || !glob && legacyExcluded("/"+rel, x.Pattern, cfg.CaseInsensitive) {
+				return x.Pattern
+			}
+		}
+	}
+	return ""
+}
+
+// policyRels devolve as formas de ps contra as quais se avalia um exclude de
+// política declarado em base: relativa a base (nenhuma se ps está fora) ou,
+// sem base, relativa a cada ancestral.
+func policyRels(ps, base string) []string {
+	if base != "" {
+		if rel, ok := strings.CutPrefix(ps, strings.TrimSuffix(util.ToSlash(base), "/")+"/"); ok {
+			return []string{rel}
+		}
+		return nil
 	}
-	// substrings típicas
-	l := strings.ToLower(path)
-	if strings.Contains(l, "secret") || strings.Contains(l, "secrets") ||
-		strings.Contains(l, "token") || strings.Contains(l, "apikey") ||
-		strings.Contains(l, "api_key") || strings.Contains(l, "password") ||
-		strings.Contains(l, "passwd") {
-		return true
+	var out []string
+	for i := 0; i < len(ps)-1; i++ {
+		if ps[i] == '/' {
+			out = append(out, ps[i+1:])
+		}
 	}
-	return false
+	return out
+}
+
+// PolicyExcludedDir informa se a varredura pode podar dir por um exclude de
+// política, que vale qualquer que seja o -p. Devolve o padrão responsável.
+func PolicyExcludedDir(dir string, cfg cli.Config) (bool, string) {
+	if len(cfg.PolicyExcludes) == 0 {
+		return false, ""
+	}
+	abs, err := util.Abs(dir)
+	if err != nil {
+		return false, ""
+	}
+	p := matchPolicy(util.ToSlash(abs), true, cfg)
+	return p != "", p
+}
+
+// ExcludedDir informa se a varredura do FS pode podar o diretório rel
+// (relativo à raiz). Só poda quando nenhum padrão "!" poderia reincluir algo
+// lá dentro, para que o resultado seja o mesmo do modo git, onde cada arquivo
+// passa por Decide. Devolve o padrão responsável.
+func ExcludedDir(rel string, cfg cli.Config) (bool, string) {
+	base := rel
+	if i := strings.LastIndexByte(rel, '/'); i >= 0 {
+		base = rel[i+1:]
+	}
+	decisive := ""
+	for _, p := range cfg.Excludes {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if match.IsGlob(p) {
+			pat, err := match.Compile(p, cfg.CaseInsensitive)
+			if err != nil {
+				continue
+			}
+			if pat.Negate {
+				return false, ""
+			}
+			if rel != "" && pat.Match(rel, true) {
+				decisive = p
+			}
+			continue
+		}
+		if strings.Contains(p, "/") {
+			continue
+		}
+		if base == p || (cfg.CaseInsensitive && strings.EqualFold(base, p)) {
+			decisive = p
+		}
+	}
+	return decisive != "", decisive
 }
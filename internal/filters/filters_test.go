@@ -85,3 +85,64 @@ func TestFilters_MaxBytesAndIncludes(t *testing.T) {
 		t.Fatal("big.txt deveria ser excluído por tamanho")
 	}
 }
+
+func TestFilters_CredentialContent(t *testing.T) {
+	dir := t.TempDir()
+	leak := mkfile(t, dir, "config.go", []byte("package cfg\n\nconst key = \"AKIA"+"IOSFODNN7EXAMPLE\"\n"))
+	clean := mkfile(t, dir, "main.go", []byte("package main\n"))
+
+	cfg := cli.Config{SecretsStrict: true, BinarySkip: true}
+	d := filters.Decide(leak, cfg)
+	if d.Include || d.Reason != "secret-content" || d.Rule != "aws-access-key-id" {
+		t.Fatalf("esperava secret-content/aws-access-key-id, got %+v", d)
+	}
+	if !filters.Decide(clean, cfg).Include {
+		t.Fatal("main.go deveria ser incluído")
+	}
+	if d := filters.Decide(filepath.Join(dir, ".env"), cfg); d.Rule != "dotenv" {
+		t.Fatalf("regra de nome esperada dotenv, got %+v", d)
+	}
+
+	// --danger-include-secrets desliga também a varredura de conteúdo
+	cfg.SecretsStrict = false
+	if !filters.Decide(leak, cfg).Include {
+		t.Fatal("com SecretsStrict=false o arquivo deveria entrar")
+	}
+}
+
+func TestFilters_RedactKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	leak := mkfile(t, dir, "config.go", []byte("const key = \"AKIA"+"IOSFODNN7EXAMPLE\"\n"))
+	_ = mkfile(t, dir, ".env", []byte("X=1\n"))
+	cfg := cli.Config{SecretsStrict: true, BinarySkip: true, Redact: true}
+	if !filters.Decide(leak, cfg).Include {
+		t.Fatal("com --redact o arquivo deveria entrar (redigido)")
+	}
+	if filters.Decide(filepath.Join(dir, ".env"), cfg).Include {
+		t.Fatal("regras de nome continuam valendo com --redact")
+	}
+}
+
+func TestFilters_NameRulesSkipSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	tok := mkfile(t, dir, "internal/tokens/tokenizer.go", []byte("package tokens\n"))
+	reset := mkfile(t, dir, "api/password_reset_handler.go", []byte("package api\n"))
+	data := mkfile(t, dir, "config/secrets.json", []byte("{}\n"))
+
+	cfg := cli.Config{Paths: []string{dir}, SecretsStrict: true, BinarySkip: true}
+	for _, p := range []string{tok, reset} {
+		if d := filters.Decide(p, cfg); !d.Include {
+			t.Errorf("%s deveria entrar, got %+v", p, d)
+		}
+	}
+	if d := filters.Decide(data, cfg); d.Include || d.Rule != "substring:secret" {
+		t.Fatalf("secrets.json deveria sair por substring:secret, got %+v", d)
+	}
+
+	// o arquivo de regras libera o caminho
+	rules := mkfile(t, dir, "rules.yaml", []byte("allow:\n  - paths: ['config/*.json']\n"))
+	cfg.SecretRules = rules
+	if d := filters.Decide(data, cfg); !d.Include {
+		t.Fatalf("allowlist deveria liberar config/secrets.json, got %+v", d)
+	}
+}
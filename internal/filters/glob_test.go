@@ -0,0 +1,49 @@
+package filters_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/filters"
+)
+
+func TestGlobExcludesWithNegation(t *testing.T) {
+	dir := t.TempDir()
+	gen := mkfile(t, dir, "api/v1/svc.pb.go", []byte("package v1\n"))
+	td := mkfile(t, dir, "pkg/testdata/in.go", []byte("package td\n"))
+	keep := mkfile(t, dir, "pkg/testdata/keep.go", []byte("package td\n"))
+	src := mkfile(t, dir, "pkg/a.go", []byte("package pkg\n"))
+
+	cfg := cli.Config{
+		Paths:    []string{dir},
+		Excludes: []string{"*.pb.go", "**/testdata/**", "!pkg/testdata/keep.go"},
+	}
+	want := map[string]bool{gen: false, td: false, keep: true, src: true}
+	for p, inc := range want {
+		if got := filters.Decide(p, cfg).Include; got != inc {
+			t.Fatalf("%s: include=%v, esperado %v", p, got, inc)
+		}
+	}
+
+	// sem negação o diretório pode ser podado; com negação, não
+	if ok, pat := filters.ExcludedDir("pkg/testdata", cli.Config{Excludes: []string{"**/testdata/**"}}); !ok || pat != "**/testdata/**" {
+		t.Fatalf("testdata deveria ser podado; ok=%v pat=%q", ok, pat)
+	}
+	if ok, _ := filters.ExcludedDir("pkg/testdata", cfg); ok {
+		t.Fatal("não deveria podar quando há padrão de negação")
+	}
+}
+
+func TestGlobIncludesAnchoredToRoot(t *testing.T) {
+	dir := t.TempDir()
+	a := mkfile(t, dir, "docs/a.md", []byte("# a\n"))
+	b := mkfile(t, dir, "x/docs/b.md", []byte("# b\n"))
+	cfg := cli.Config{Paths: []string{dir}, Includes: []string{"docs/*.md"}}
+	if !filters.Decide(a, cfg).Include {
+		t.Fatal("docs/a.md deveria entrar")
+	}
+	if filters.Decide(filepath.Clean(b), cfg).Include {
+		t.Fatal("x/docs/b.md não deveria entrar (padrão ancorado)")
+	}
+}
@@ -0,0 +1,32 @@
+package format
+
+import (
+	"github.com/harrison-m-freitas/codectx/internal/anon"
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// anonymizer devolve o dicionário de --anonymize, ou nil sem ele (os métodos
+// de *anon.Dict aceitam nil). Um dicionário inválido já foi recusado por
+// cli.Validate.
+func anonymizer(cfg cli.Config) *anon.Dict {
+	if cfg.Anonymize == "" {
+		return nil
+	}
+	d, err := anon.Load(cfg.Anonymize)
+	if err != nil {
+		return nil
+	}
+	return d
+}
+
+// DisplayPath devolve p como aparece no documento: com "/" e, com
+// --anonymize-paths, com os termos do dicionário trocados pelos pseudônimos.
+// Metrics e logs continuam com o caminho real.
+func DisplayPath(p string, cfg cli.Config) string {
+	p = util.ToSlash(p)
+	if !cfg.AnonymizePaths {
+		return p
+	}
+	return anonymizer(cfg).Text(p)
+}
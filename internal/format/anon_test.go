@@ -0,0 +1,78 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestAnonymizePathsAndBody(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "terms.txt")
+	_ = os.WriteFile(dict, []byte("Initech\n"), 0o644)
+	src := filepath.Join(dir, "initech-api", "client.go")
+	_ = os.MkdirAll(filepath.Dir(src), 0o755)
+	data := "package initechapi\n// Cliente da Initech\n"
+	_ = os.WriteFile(src, []byte(data), 0o644)
+	fm := scan.FileMeta{Path: src, Size: int64(len(data))}
+
+	cfg := cli.Config{Format: "plain", Paths: []string{filepath.Dir(src)}, Anonymize: dict}
+	var w bytes.Buffer
+	if _, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{fm}, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), "package anon001api\n// Cliente da Anon001\n") {
+		t.Fatalf("corpo não anonimizado:\n%s", w.String())
+	}
+	if !strings.Contains(w.String(), "initech-api/client.go") {
+		t.Fatalf("sem --anonymize-paths o caminho fica como está:\n%s", w.String())
+	}
+
+	cfg.AnonymizePaths = true
+	w.Reset()
+	_ = format.WriteDocHeader(&w, cfg)
+	if _, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{fm}, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out := w.String(); strings.Contains(strings.ToLower(out), "initech") || !strings.Contains(out, "anon001-api/client.go") {
+		t.Fatalf("caminhos deveriam usar o pseudônimo:\n%s", out)
+	}
+}
+
+func TestAnonymizePathsInSplitIndexAndTemplates(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "terms.txt")
+	_ = os.WriteFile(dict, []byte("Initech\n"), 0o644)
+	root := filepath.Join(dir, "initech-api")
+	_ = os.MkdirAll(root, 0o755)
+	cfg := cli.Config{Paths: []string{root}, Anonymize: dict, AnonymizePaths: true}
+	parts := []format.SplitEntry{{Name: "anon001-api", File: "anon001-api.md", Files: 1}}
+	for _, f := range []string{"plain", "markdown", "json", "ndjson", "xml", "html"} {
+		cfg.Format = f
+		var w bytes.Buffer
+		if err := format.WriteSplitIndex(&w, cfg, parts); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(strings.ToLower(w.String()), "initech") {
+			t.Errorf("%s: índice expõe a raiz:\n%s", f, w.String())
+		}
+	}
+
+	// os templates só veem a configuração saneada
+	tpl := filepath.Join(dir, "t.tmpl")
+	_ = os.WriteFile(tpl, []byte(`{{define "file"}}{{.Config.Anonymize}}{{end}}`), 0o644)
+	cfg.Format, cfg.Template = "template", tpl
+	src := filepath.Join(root, "a.go")
+	_ = os.WriteFile(src, []byte("package a\n"), 0o644)
+	var w bytes.Buffer
+	if _, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{{Path: src, Size: 10}}, cfg, nil); err == nil {
+		t.Fatalf("template não deveria acessar .Config.Anonymize:\n%s", w.String())
+	}
+}
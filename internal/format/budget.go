@@ -0,0 +1,232 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/tokens"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// Contagem de tokens: o tokenizador BPE só roda quando pedido (--tokenizer
+// ou --max-tokens); sem isso, os números informativos usam tokens.Estimate.
+// O Tokens de cada arquivo conta o conteúdo emitido e os totais somam esses
+// números; só o orçamento conta o bloco inteiro (cabeçalho incluído). Uma
+// estimativa não vira o Tokens de um arquivo: sem contagem exata ele é
+// omitido e só o total, marcado como estimativa, aparece no rodapé.
+
+// exactTokens informa se cfg pede a contagem pelo tokenizador.
+func exactTokens(cfg cli.Config) bool {
+	return cfg.Tokenizer != "" || cfg.MaxTokens > 0
+}
+
+// exactTokenizer devolve a codificação da contagem exata, ou "" quando os
+// tokens são estimados (os arquivos então não trazem Tokens).
+func exactTokenizer(cfg cli.Config) string {
+	if !exactTokens(cfg) {
+		return ""
+	}
+	return tokenizerName(cfg)
+}
+
+// countTokens conta os tokens de s com o tokenizador de cfg (--tokenizer),
+// ou os estima.
+func countTokens(cfg cli.Config, s string) int {
+	if !exactTokens(cfg) {
+		return tokens.Estimate(s)
+	}
+	tk, err := tokens.Get(cfg.Tokenizer)
+	if err != nil {
+		return tokens.Estimate(s) // Validate já rejeita tokenizadores desconhecidos
+	}
+	return tk.Count(s)
+}
+
+// blockTokens conta os tokens de um bloco renderizado para --max-tokens (0
+// sem orçamento).
+func blockTokens(cfg cli.Config, s string) int {
+	if cfg.MaxTokens <= 0 {
+		return 0
+	}
+	return countTokens(cfg, s)
+}
+
+// budget aplica --max-tokens ao documento inteiro. O cabeçalho e o rodapé
+// sem arquivos e a linha de cada arquivo entre os omitidos do rodapé são
+// reservados antes de tudo; a reserva de um arquivo é devolvida quando ele
+// entra, e ele paga o que acrescenta ao cabeçalho e ao rodapé (--tree, --toc,
+// a navegação do html, as redações). Os blocos (cabeçalho do arquivo
+// incluído) entram na ordem de saída, fixados primeiro e depois --order,
+// enquanto cabem; o primeiro que não cabe é truncado por linhas se isso o
+// fizer caber, e todos os seguintes são omitidos. Como os tokens de partes
+// somadas podem diferir por um em cada emenda da contagem do texto inteiro,
+// o total é uma estimativa muito próxima.
+type budget struct {
+	max     int
+	left    int
+	full    bool
+	omitted []string
+	cut     string
+
+	cfg      cli.Config
+	doc      DocInfo
+	foot     FooterInfo
+	head0    int   // tokens do cabeçalho sem arquivos
+	foot0    int   // tokens do rodapé sem arquivos
+	reserved []int // linha de cada arquivo entre os omitidos, por posição
+}
+
+// newBudget devolve nil quando não há orçamento. doc e foot descrevem o
+// cabeçalho e o rodapé do documento; os números que só se conhecem no fim
+// (tokens usados, descartes na leitura) entram pelo seu máximo.
+func newBudget(cfg cli.Config, files []scan.FileMeta, doc DocInfo, foot FooterInfo) *budget {
+	if cfg.MaxTokens <= 0 {
+		return nil
+	}
+	doc.Files, doc.Failed = nil, nil
+	foot.Tokens, foot.Tokenizer, foot.Budget = cfg.MaxTokens, tokenizerName(cfg), cfg.MaxTokens
+	foot.SkippedSecret += len(files)
+	foot.Files, foot.Failed, foot.Omitted, foot.Cut, foot.Redacted = nil, nil, nil, "", nil
+	bg := &budget{max: cfg.MaxTokens, cfg: cfg, doc: doc, foot: foot}
+	bg.head0 = bg.headTokens(doc)
+	bg.foot0 = bg.footTokens(foot)
+	bg.left = bg.max - bg.head0 - bg.foot0
+	bg.reserved = make([]int, len(files))
+	for i, fm := range files {
+		// a linha do arquivo na lista: a lista com ele duas vezes menos com
+		// ele uma; o título da lista é reservado uma vez só, com folga para
+		// os dígitos da contagem
+		p := util.ToSlash(fm.Path)
+		f := foot
+		f.Omitted = []string{p}
+		once := bg.footDelta(f)
+		f.Omitted = []string{p, p}
+		bg.reserved[i] = max(bg.footDelta(f)-once, 0)
+		if i == 0 {
+			bg.left -= once - bg.reserved[i] + 1
+		}
+		bg.left -= bg.reserved[i]
+	}
+	return bg
+}
+
+// headTokens conta os tokens do cabeçalho do documento com doc.
+func (bg *budget) headTokens(doc DocInfo) int {
+	var b strings.Builder
+	_ = WriteDocHeaderInfo(&b, bg.cfg, doc) // erros aparecem na escrita de verdade
+	return countTokens(bg.cfg, b.String())
+}
+
+// footTokens conta os tokens do rodapé do documento com foot.
+func (bg *budget) footTokens(foot FooterInfo) int {
+	var b strings.Builder
+	_ = WriteSummaryFooterInfo(&b, bg.cfg, foot)
+	return countTokens(bg.cfg, b.String())
+}
+
+// footDelta devolve o que foot acrescenta ao rodapé sem arquivos.
+func (bg *budget) footDelta(foot FooterInfo) int {
+	return max(bg.footTokens(foot)-bg.foot0, 0)
+}
+
+// listed devolve o que o bloco de fm acrescenta ao documento fora dele: a
+// entrada no cabeçalho e no rodapé, as redações, a vírgula entre registros
+// em json e, se cut, a linha de truncado. As entradas de vários arquivos
+// somadas passam do custo real (diretórios comuns da árvore contam uma vez
+// por arquivo), nunca ficam abaixo dele.
+func (bg *budget) listed(fm scan.FileMeta, blk block, cut bool) int {
+	doc := bg.doc
+	doc.Files = []scan.FileMeta{fm}
+	n := max(bg.headTokens(doc)-bg.head0, 0)
+	foot := bg.foot
+	foot.Files = []scan.FileMeta{fm}
+	if len(blk.redactions) > 0 {
+		foot.Redacted = []FileRedactions{{Path: util.ToSlash(fm.Path), Items: blk.redactions}}
+	}
+	if cut {
+		foot.Cut = util.ToSlash(fm.Path)
+	}
+	n += bg.footDelta(foot)
+	if bg.cfg.Format == "json" {
+		n += countTokens(bg.cfg, ",\n")
+	}
+	return n
+}
+
+// used devolve os tokens do documento até aqui: a reserva e os blocos
+// admitidos.
+func (bg *budget) used() int { return bg.max - bg.left }
+
+// admit decide o destino do bloco da posição pos (fm): inteiro, truncado ou
+// omitido (buf nil). Deve ser chamado na ordem de saída.
+func (bg *budget) admit(pos int, fm scan.FileMeta, cfg cli.Config, blk block) (block, error) {
+	// o arquivo não vai para a lista de omitidos se entrar ou se não tiver
+	// nada a mostrar
+	left := bg.left + bg.reserved[pos]
+	if blk.buf == nil {
+		bg.left = left
+		return blk, nil
+	}
+	if !bg.full {
+		if need := blk.tokens + bg.listed(fm, blk, false); need <= left {
+			bg.left = left - need
+			return blk, nil
+		}
+		bg.full = true
+		cut, err := truncateToFit(fm, cfg, blk, left-bg.listed(fm, blk, true))
+		if err != nil {
+			return block{}, err
+		}
+		if cut.buf != nil {
+			bg.left = left - cut.tokens - bg.listed(fm, cut, true)
+			bg.cut = util.ToSlash(fm.Path)
+			return cut, nil
+		}
+	}
+	bg.omitted = append(bg.omitted, util.ToSlash(fm.Path))
+	return block{}, nil
+}
+
+// truncateToFit procura, por busca binária, o maior --max-lines com que o
+// bloco full de fm cabe em left tokens, renderizando de novo a mesma leitura
+// (full.src). Devolve buf nil se nem uma linha couber.
+func truncateToFit(fm scan.FileMeta, cfg cli.Config, full block, left int) (block, error) {
+	hi := bytes.Count(full.buf, []byte{'\n'})
+	if full.src != nil {
+		// em json o bloco é uma linha só: o limite vem do conteúdo e do diff
+		hi = max(bytes.Count(full.src.data, []byte{'\n'}), strings.Count(full.src.diff, "\n")) + 1
+	}
+	if cfg.MaxLines > 0 && cfg.MaxLines-1 < hi {
+		hi = cfg.MaxLines - 1
+	}
+	var best block
+	for lo := 1; lo <= hi; {
+		n := (lo + hi) / 2
+		c := cfg
+		c.MaxLines = n
+		blk, err := renderOne(fm, c, full.src)
+		if err != nil {
+			return block{}, err
+		}
+		if blk.buf != nil && blk.tokens <= left {
+			best = blk
+			lo = n + 1
+		} else {
+			hi = n - 1
+		}
+	}
+	return best, nil
+}
+
+// tokenizerName devolve o nome da codificação usada para cfg.
+func tokenizerName(cfg cli.Config) string {
+	if !exactTokens(cfg) {
+		return tokens.Estimated
+	}
+	if tk, err := tokens.Get(cfg.Tokenizer); err == nil {
+		return tk.Name()
+	}
+	return cfg.Tokenizer
+}
@@ -0,0 +1,152 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/tokens"
+)
+
+func TestMaxTokensPacksPinnedFirstAndTruncatesLast(t *testing.T) {
+	dir := t.TempDir()
+	body := strings.Repeat("linha de código\n", 40) // ~160 tokens
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "main.txt"} {
+		_ = os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644)
+	}
+	cfg := cli.Config{
+		Paths:     []string{dir},
+		Format:    "plain",
+		Order:     "path",
+		Pins:      []string{"main.txt"},
+		MaxTokens: 550,
+	}
+	files, _, err := scan.List(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(files[0].Path) != "main.txt" {
+		t.Fatalf("arquivo fixado deveria vir primeiro: %v", files)
+	}
+
+	var w bytes.Buffer
+	m, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Budget > cfg.MaxTokens || m.Budget <= m.Tokens {
+		t.Fatalf("orçamento usado=%d deveria caber em %d e somar os cabeçalhos ao conteúdo (%d)", m.Budget, cfg.MaxTokens, m.Tokens)
+	}
+	if m.Files != 2 || !strings.HasSuffix(m.Cut, "/a.txt") {
+		t.Fatalf("esperava main.txt inteiro e a.txt truncado; files=%d cut=%q", m.Files, m.Cut)
+	}
+	if len(m.Omitted) != 2 || !strings.HasSuffix(m.Omitted[0], "/b.txt") {
+		t.Fatalf("omitidos inesperados: %v", m.Omitted)
+	}
+	if !strings.Contains(w.String(), "[... truncado em ") {
+		t.Fatalf("a.txt deveria trazer o marcador de truncamento:\n%s", w.String())
+	}
+
+	var foot bytes.Buffer
+	_ = format.WriteSummaryFooterInfo(&foot, cfg, format.FooterInfo{Tokens: m.Tokens, Budget: m.Budget, Omitted: m.Omitted, Cut: m.Cut})
+	if !strings.Contains(foot.String(), "Omitidos pelo orçamento (2):") || !strings.Contains(foot.String(), "c.txt") {
+		t.Fatalf("rodapé deveria listar os omitidos:\n%s", foot.String())
+	}
+}
+
+// O total é a soma dos Tokens de cada arquivo; sem --tokenizer nem
+// --max-tokens só o total, estimado, é informado: os arquivos não trazem
+// tokens.
+func TestTokenTotalsAddUp(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() int { return 1 }\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "b.txt"), []byte(strings.Repeat("texto comum\n", 30)), 0o644)
+	for _, c := range []struct{ tokenizer, name string }{{"", "estimativa"}, {"cl100k_base", "cl100k_base"}} {
+		cfg := cli.Config{Paths: []string{dir}, Format: "json", Order: "path", Tokenizer: c.tokenizer}
+		files, _, err := scan.List(context.TODO(), cfg, logx.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var w bytes.Buffer
+		m, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var recs []struct{ Tokens *int }
+		if err := json.Unmarshal([]byte("["+w.String()+"]"), &recs); err != nil {
+			t.Fatalf("%v\n%s", err, w.String())
+		}
+		if len(recs) != 2 || m.Tokens == 0 {
+			t.Fatalf("%s: total %d (%d registros)", c.name, m.Tokens, len(recs))
+		}
+		sum := 0
+		for _, r := range recs {
+			if (r.Tokens != nil) != (c.tokenizer != "") {
+				t.Fatalf("%s: tokens por arquivo só com contagem exata:\n%s", c.name, w.String())
+			}
+			if r.Tokens != nil {
+				sum += *r.Tokens
+			}
+		}
+		if c.tokenizer != "" && m.Tokens != sum {
+			t.Fatalf("%s: total %d, soma dos arquivos %d", c.name, m.Tokens, sum)
+		}
+		if m.Tokenizer != c.name {
+			t.Fatalf("tokenizador: got %q, want %q", m.Tokenizer, c.name)
+		}
+	}
+}
+
+// O orçamento vale para o documento inteiro: cabeçalho com árvore e sumário,
+// blocos e rodapé com os omitidos cabem em --max-tokens, em todos os
+// formatos.
+func TestMaxTokensCoversWholeDocument(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "pkg/d.txt", "pkg/e.txt"} {
+		p := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0o755)
+		_ = os.WriteFile(p, []byte(strings.Repeat("linha de código\n", 30)), 0o644)
+	}
+	tk, err := tokens.Get("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"plain", "markdown", "fenced", "json", "ndjson", "xml", "html"} {
+		cfg := cli.Config{Paths: []string{dir}, Format: f, Order: "path", Tree: true, TOC: true, MaxTokens: 600}
+		if f == "html" {
+			cfg.MaxTokens = 1800 // estilos e script embutidos
+		}
+		files, _, err := scan.List(context.TODO(), cfg, logx.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := format.DocInfo{Files: files}
+		var body bytes.Buffer
+		m, err := format.ProcessFilesInfo(context.TODO(), &body, files, cfg, doc, format.FooterInfo{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Omitted) == 0 {
+			t.Fatalf("%s: o orçamento deveria deixar arquivos de fora", f)
+		}
+		var w bytes.Buffer
+		doc.Files = m.Written
+		_ = format.WriteDocHeaderInfo(&w, cfg, doc)
+		w.Write(body.Bytes())
+		_ = format.WriteSummaryFooterInfo(&w, cfg, format.FooterInfo{
+			Tokens: m.Tokens, Tokenizer: m.Tokenizer, Budget: m.Budget,
+			Omitted: m.Omitted, Cut: m.Cut, Files: m.Written,
+		})
+		if n := tk.Count(w.String()); n > cfg.MaxTokens || m.Budget > cfg.MaxTokens {
+			t.Fatalf("%s: documento com %d tokens (orçamento usado %d) passa de %d:\n%s", f, n, m.Budget, cfg.MaxTokens, w.String())
+		}
+	}
+}
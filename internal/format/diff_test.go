@@ -0,0 +1,123 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestWithDiffAndDiffOnly(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "t@t.t")
+	run("config", "user.name", "t")
+	edit := filepath.Join(dir, "edit.go")
+	same := filepath.Join(dir, "same.go")
+	_ = os.WriteFile(edit, []byte("package a\n\nvar X = 1\n"), 0o644)
+	_ = os.WriteFile(same, []byte("package a\n"), 0o644)
+	run("add", ".")
+	run("commit", "-qm", "base")
+	_ = os.WriteFile(edit, []byte("package a\n\nvar X = 2\n"), 0o644)
+
+	files := []scan.FileMeta{{Path: edit, Index: 0}, {Path: same, Index: 1}}
+	render := func(cfg cli.Config) string {
+		t.Helper()
+		var w bytes.Buffer
+		if _, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil); err != nil {
+			t.Fatal(err)
+		}
+		return w.String()
+	}
+
+	out := render(cli.Config{Format: "markdown", WithDiff: true, DiffContext: 3})
+	if !strings.Contains(out, "var X = 2\n") || !strings.Contains(out, "**Diff:**\n\n```diff\n@@") {
+		t.Fatalf("--with-diff deveria manter o conteúdo e anexar o diff:\n%s", out)
+	}
+	if strings.Count(out, "```diff") != 1 {
+		t.Fatalf("arquivo sem alterações não deveria ter bloco diff:\n%s", out)
+	}
+
+	out = render(cli.Config{Format: "fenced", DiffOnly: true, DiffContext: 0})
+	if !strings.Contains(out, "```diff\n# File: ") || strings.Contains(out, "same.go") {
+		t.Fatalf("--diff-only deveria mostrar só arquivos alterados em bloco diff:\n%s", out)
+	}
+	if !strings.Contains(out, "-var X = 1\n+var X = 2\n") || strings.Contains(out, "\n package a") {
+		t.Fatalf("--diff-context 0 não deveria incluir contexto:\n%s", out)
+	}
+
+	out = render(cli.Config{Format: "json", DiffOnly: true, DiffContext: 3})
+	var recs []map[string]any
+	if err := json.Unmarshal([]byte("["+out+"]"), &recs); err != nil {
+		t.Fatalf("json inválido: %v\n%s", err, out)
+	}
+	if len(recs) != 1 || recs[0]["content"] != nil || !strings.Contains(recs[0]["diff"].(string), "+var X = 2") {
+		t.Fatalf("registro inesperado: %v", recs)
+	}
+}
+
+// Uma linha removida com segredo só existe no diff: a varredura de conteúdo
+// vale para os hunks também, e --redact os substitui.
+func TestDiffHunksCheckedForSecrets(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "t@t.t")
+	run("config", "user.name", "t")
+	key := "AKIA" + "IOSFODNN7EXAMPLE"
+	p := filepath.Join(dir, "k.go")
+	_ = os.WriteFile(p, []byte("package a\n\nconst k = \""+key+"\"\n"), 0o644)
+	run("add", ".")
+	run("commit", "-qm", "base")
+	_ = os.WriteFile(p, []byte("package a\n"), 0o644)
+
+	files := []scan.FileMeta{{Path: p}}
+	for _, f := range []string{"plain", "json"} {
+		cfg := cli.Config{Format: f, Paths: []string{dir}, Since: "HEAD", DiffOnly: true, SecretsStrict: true}
+		var w bytes.Buffer
+		m, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(w.String(), key) || len(m.SecretSkips) != 1 || m.SecretSkips[0].Decision.Rule != "aws-access-key-id" {
+			t.Fatalf("%s: linha removida com segredo deveria descartar o arquivo; skips=%v\n%s", f, m.SecretSkips, w.String())
+		}
+
+		cfg.Redact = true
+		w.Reset()
+		m, err = format.ProcessFiles(context.TODO(), &w, files, cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(w.String(), key) || !strings.Contains(w.String(), "[REDACTED:aws-access-key-id#1]") || len(m.SecretSkips) != 0 {
+			t.Fatalf("%s: --redact deveria substituir o segredo no diff:\n%s", f, w.String())
+		}
+	}
+}
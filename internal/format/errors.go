@@ -0,0 +1,103 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/tmpl"
+)
+
+// ErrLineTooLong indica uma linha acima de maxLineBytes.
+var ErrLineTooLong = errors.New("linha longa demais")
+
+// maxLineBytes limita uma única linha: acima disso o arquivo falha em vez de
+// ocupar memória sem limite.
+const maxLineBytes = 16 << 20
+
+// FileError é a falha de um único arquivo: sem permissão, removido durante a
+// execução, linha longa demais. Com --keep-going vira um registro no pacote e
+// a execução continua.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string { return e.Path + ": " + e.Reason() }
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// Reason descreve a falha sem repetir o caminho.
+func (e *FileError) Reason() string {
+	var pe *fs.PathError
+	if errors.As(e.Err, &pe) {
+		return pe.Op + ": " + pe.Err.Error()
+	}
+	return e.Err.Error()
+}
+
+// errorBlock renderiza o registro de uma falha no lugar do bloco do arquivo.
+func errorBlock(fm scan.FileMeta, cfg cli.Config, fe *FileError) (block, error) {
+	var b strings.Builder
+	path := DisplayPath(fe.Path, cfg)
+	switch cfg.Format {
+	case "json", "ndjson":
+		rec := jsonRec{
+			Path:  path,
+			Size:  fm.Size,
+			MTime: fm.MTime,
+			Ext:   strings.TrimPrefix(strings.ToLower(filepath.Ext(fm.Path)), "."),
+			Index: fm.Index,
+			Error: fe.Reason(),
+		}
+		j, err := json.Marshal(rec)
+		if err != nil {
+			return block{}, err
+		}
+		b.Write(j)
+		if cfg.Format == "ndjson" {
+			b.WriteByte('\n')
+		}
+	case "xml":
+		xmlRecord(&b, &jsonRec{
+			Path:  path,
+			Size:  fm.Size,
+			MTime: fm.MTime,
+			Ext:   strings.TrimPrefix(strings.ToLower(filepath.Ext(fm.Path)), "."),
+			Index: fm.Index,
+			Error: fe.Reason(),
+		})
+	case "html":
+		htmlRecord(&b, &jsonRec{Path: path, Index: fm.Index, Error: fe.Reason()}, nil)
+	case "template":
+		rec := &jsonRec{
+			Path:  path,
+			Size:  fm.Size,
+			MTime: fm.MTime,
+			Ext:   strings.TrimPrefix(strings.ToLower(filepath.Ext(fm.Path)), "."),
+			Index: fm.Index,
+			Error: fe.Reason(),
+		}
+		if err := execTemplate(&b, cfg, "file", templateFile(fm, cfg, rec)); err != nil {
+			return block{}, err
+		}
+	case "markdown":
+		fmt.Fprintf(&b, "\n## %s\n\n", path)
+		fmt.Fprintf(&b, " - **Erro:** %s\n", fe.Reason())
+	case "fenced":
+		fence := tmpl.Fence(path + "\n" + fe.Reason())
+		fmt.Fprintf(&b, "\n%stext\n# File: %s\n# Erro: %s\n%s\n", fence, path, fe.Reason(), fence)
+	default:
+		b.WriteString("\n================================================================================\n")
+		fmt.Fprintf(&b, "FILE: %s\n", path)
+		fmt.Fprintf(&b, "ERRO: %s\n", fe.Reason())
+		b.WriteString("--------------------------------------------------------------------------------\n")
+	}
+	out := b.String()
+	return block{buf: []byte(out), tokens: blockTokens(cfg, out)}, nil
+}
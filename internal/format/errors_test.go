@@ -0,0 +1,73 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+// vanished devolve três arquivos em que o do meio sumiu depois da varredura.
+func vanished(t *testing.T) []scan.FileMeta {
+	dir := t.TempDir()
+	var metas []scan.FileMeta
+	for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		p := filepath.Join(dir, name)
+		_ = os.WriteFile(p, []byte(name+"\n"), 0o644)
+		metas = append(metas, scan.FileMeta{Path: p, Size: 6, Index: i})
+	}
+	_ = os.Remove(metas[1].Path)
+	return metas
+}
+
+func TestFileErrorStopsRun(t *testing.T) {
+	metas := vanished(t)
+	var w bytes.Buffer
+	_, err := format.ProcessFiles(context.TODO(), &w, metas, cli.Config{Format: "plain", Jobs: 4}, nil)
+	var fe *format.FileError
+	if !errors.As(err, &fe) || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("esperava FileError de arquivo inexistente, got %v", err)
+	}
+	if !strings.HasSuffix(fe.Path, "/b.txt") {
+		t.Fatalf("caminho inesperado: %s", fe.Path)
+	}
+}
+
+func TestKeepGoingRecordsFailure(t *testing.T) {
+	metas := vanished(t)
+	var w bytes.Buffer
+	m, err := format.ProcessFiles(context.TODO(), &w, metas, cli.Config{Format: "plain", KeepGoing: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Files != 2 || len(m.Failed) != 1 {
+		t.Fatalf("esperava 2 arquivos e 1 falha, got %d e %d", m.Files, len(m.Failed))
+	}
+	out := w.String()
+	if !strings.Contains(out, "b.txt\nERRO: open: ") || !strings.Contains(out, "c.txt") {
+		t.Fatalf("registro de erro ausente:\n%s", out)
+	}
+
+	w.Reset()
+	w.WriteString("[")
+	if _, err := format.ProcessFiles(context.TODO(), &w, metas, cli.Config{Format: "json", KeepGoing: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("]")
+	var recs []map[string]any
+	if err := json.Unmarshal(w.Bytes(), &recs); err != nil {
+		t.Fatalf("JSON inválido: %v\n%s", err, w.String())
+	}
+	if len(recs) != 3 || recs[1]["error"] == nil || recs[0]["error"] != nil {
+		t.Fatalf("campo error inesperado: %v", recs)
+	}
+}
@@ -0,0 +1,78 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+// WriteExplain escreve o resultado de `codectx explain`: JSON (array, um
+// objeto por caminho) com -F json/ndjson e texto legível nos demais formatos.
+func WriteExplain(w io.Writer, cfg cli.Config, exs []*scan.Explanation) error {
+	switch cfg.Format {
+	case "json":
+		b, err := json.MarshalIndent(exs, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case "ndjson":
+		for _, ex := range exs {
+			b, err := json.Marshal(ex)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i, ex := range exs {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := writeExplainText(w, ex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeExplainText(w io.Writer, ex *scan.Explanation) error {
+	verdict := "INCLUÍDO"
+	if !ex.Included {
+		verdict = "DESCARTADO (" + ex.Reason + ")"
+	}
+	if _, err := fmt.Fprintf(w, "%s: %s\n", ex.Path, verdict); err != nil {
+		return err
+	}
+	if ex.Mode != "" {
+		if _, err := fmt.Fprintf(w, "  modo: %s | raiz: %s\n", ex.Mode, ex.Root); err != nil {
+			return err
+		}
+	}
+	for _, st := range ex.Steps {
+		mark := "ok  "
+		switch {
+		case !st.Active:
+			mark = "--  "
+		case !st.Pass:
+			mark = "FAIL"
+		}
+		line := fmt.Sprintf("  [%s] %s", mark, st.Name)
+		if st.Detail != "" {
+			line = fmt.Sprintf("  [%s] %-9s %s", mark, st.Name, st.Detail)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,26 +4,85 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
-	"os"
+	"math"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/filters"
+	"github.com/harrison-m-freitas/codectx/internal/gitx"
 	"github.com/harrison-m-freitas/codectx/internal/logx"
 	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/tmpl"
+	"github.com/harrison-m-freitas/codectx/internal/tokens"
 	"github.com/harrison-m-freitas/codectx/internal/util"
 )
 
 type Metrics struct {
 	Files int
 	Bytes int64
+
+	Tokens    int    // soma dos Tokens dos arquivos emitidos (só o conteúdo)
+	Tokenizer string // codificação usada na contagem (tokens.Estimated sem tokenizador)
+
+	// --max-tokens
+	Budget  int      // tokens do documento: blocos admitidos, cabeçalho e rodapé
+	Omitted []string // arquivos deixados de fora pelo orçamento
+	Cut     string   // arquivo truncado para caber no orçamento
+
+	Failed      []*FileError     // --keep-going: arquivos registrados como falha
+	Redacted    []FileRedactions // --redact: segredos substituídos, por arquivo
+	SecretSkips []SecretSkip     // descartados ao serem lidos (secret-content)
+
+	Written []scan.FileMeta // arquivos com bloco no documento, na ordem de saída
+}
+
+// SecretSkip é um arquivo descartado por segredo no conteúdo, detectado na
+// leitura para a emissão; o chamador o soma aos contadores da listagem
+// (scan.Counters.Skip).
+type SecretSkip struct {
+	Path     string
+	Decision filters.Decision
+}
+
+// DocInfo traz metadados do documento que não vêm da configuração.
+type DocInfo struct {
+	Changes  string   // modo de alterações ativo (ex.: "since v1.2")
+	Deleted  []string // arquivos removidos no modo de alterações
+	Revision string   // --rev: revisão pedida e SHA do commit resolvido
+
+	Files   []scan.FileMeta // emitidos, na ordem de saída (--tree, --toc; veja HeaderListsFiles)
+	Failed  []*FileError    // --keep-going: os de Files que falharam
+	Skipped []scan.Skipped  // ignorados (--tree-skipped)
+}
+
+// HeaderListsFiles informa se o cabeçalho de cfg lista os arquivos (--tree,
+// --toc, o bloco "header" de -F template). Nesse caso ele deve ser escrito
+// depois de ProcessFiles, com DocInfo.Files = Metrics.Written: o orçamento,
+// --diff-only e as falhas deixam arquivos selecionados fora do documento.
+func HeaderListsFiles(cfg cli.Config) bool {
+	switch cfg.Format {
+	case "template":
+		return true
+	case "json", "ndjson", "xml", "html":
+		return false
+	}
+	return cfg.Tree || cfg.TOC
 }
 
 func WriteDocHeader(w io.Writer, cfg cli.Config) error {
+	return WriteDocHeaderInfo(w, cfg, DocInfo{})
+}
+
+// WriteDocHeaderInfo escreve o cabeçalho do documento com os metadados de info.
+func WriteDocHeaderInfo(w io.Writer, cfg cli.Config, info DocInfo) error {
   if cfg.Format == "json" {
     _, err := io.WriteString(w, "[\n")
     return err
@@ -31,7 +90,20 @@ func WriteDocHeader(w io.Writer, cfg cli.Config) error {
   if cfg.Format == "ndjson" {
     return nil
   }
-	paths := strings.Join(cfg.Paths, ":")
+  if cfg.Format == "xml" {
+    return writeXMLHeader(w, cfg, info)
+  }
+  if cfg.Format == "html" {
+    return writeHTMLHeader(w, cfg, info)
+  }
+  if cfg.Format == "template" {
+    return writeTemplateHeader(w, cfg, info)
+  }
+	roots := make([]string, len(cfg.Paths))
+	for i, r := range cfg.Paths {
+		roots[i] = DisplayPath(r, cfg)
+	}
+	paths := strings.Join(roots, ":")
 	_, err := fmt.Fprintf(w, "# Code Context\n# Generated: %s\n# Paths: %s\n", util.Now().Format("2006-01-02 15:04:05 -0700"), paths)
 	if cfg.ExtCSV != "" {
 		_, _ = fmt.Fprintf(w, "# Extensions: %s\n", cfg.ExtCSV)
@@ -39,11 +111,55 @@ func WriteDocHeader(w io.Writer, cfg cli.Config) error {
 	if cfg.Depth > 0 {
 		_, _ = fmt.Fprintf(w, "# Max Depth: %d\n", cfg.Depth)
 	}
+	if cfg.PolicyHash != "" {
+		files := make([]string, len(cfg.PolicyFiles))
+		for i, f := range cfg.PolicyFiles {
+			files[i] = DisplayPath(f, cfg)
+		}
+		_, _ = fmt.Fprintf(w, "# Policy: %s (%s)\n", cfg.PolicyHash, strings.Join(files, ", "))
+	}
+	if info.Revision != "" {
+		_, _ = fmt.Fprintf(w, "# Revision: %s\n", info.Revision)
+	}
+	if info.Changes != "" {
+		_, _ = fmt.Fprintf(w, "# Changes: %s\n", info.Changes)
+	}
+	if len(info.Deleted) > 0 {
+		_, _ = fmt.Fprintf(w, "# Deleted (%d):\n", len(info.Deleted))
+		for _, d := range info.Deleted {
+			_, _ = fmt.Fprintf(w, "#   - %s\n", DisplayPath(d, cfg))
+		}
+	}
+	if cfg.Tree && err == nil {
+		err = writeTree(w, cfg, info)
+	}
+	if cfg.TOC && err == nil {
+		err = writeTOC(w, cfg, info)
+	}
 	_, _ = fmt.Fprintln(w)
 	return err
 }
 
 func WriteSummaryFooter(w io.Writer, cfg cli.Config, skippedBin, skippedSec int) error {
+	return WriteSummaryFooterInfo(w, cfg, FooterInfo{SkippedBin: skippedBin, SkippedSecret: skippedSec})
+}
+
+// FooterInfo traz os números do rodapé do documento.
+type FooterInfo struct {
+	SkippedBin    int
+	SkippedSecret int
+	Tokens        int      // soma dos Tokens dos arquivos emitidos
+	Tokenizer     string   // codificação da contagem ("" omite a linha)
+	Budget        int      // --max-tokens: tokens do documento
+	Omitted       []string // --max-tokens: arquivos que ficaram de fora
+	Cut           string   // --max-tokens: arquivo truncado para caber
+	Failed        []*FileError // --keep-going: arquivos que falharam
+	Redacted      []FileRedactions // --redact: segredos substituídos
+	Files         []scan.FileMeta  // arquivos emitidos (navegação do formato html)
+}
+
+// WriteSummaryFooterInfo escreve o rodapé do documento com os dados de info.
+func WriteSummaryFooterInfo(w io.Writer, cfg cli.Config, info FooterInfo) error {
   if cfg.Format == "json" {
     _, err := io.WriteString(w, "\n]\n")
     return err
@@ -51,23 +167,232 @@ func WriteSummaryFooter(w io.Writer, cfg cli.Config, skippedBin, skippedSec int)
   if cfg.Format == "ndjson" {
     return nil
   }
+  if cfg.Format == "xml" {
+    return writeXMLFooter(w, cfg, info)
+  }
+  if cfg.Format == "html" {
+    return writeHTMLFooter(w, cfg, info)
+  }
+  if cfg.Format == "template" {
+    return writeTemplateFooter(w, cfg, info)
+  }
+	var err error
 	switch cfg.Format {
 	case "markdown":
-		_, err := fmt.Fprintf(w, "\n---\n**Resumo adicional:** binários ignorados: %d · arquivos sensíveis ignorados: %d\n", skippedBin, skippedSec)
+		_, err = fmt.Fprintf(w, "\n---\n**Resumo adicional:** binários ignorados: %d · arquivos sensíveis ignorados: %d\n", info.SkippedBin, info.SkippedSecret)
+	default:
+		_, err = fmt.Fprintf(w, "\n---\nResumo adicional: binários ignorados=%d ; arquivos sensíveis ignorados=%d\n", info.SkippedBin, info.SkippedSecret)
+	}
+	if err == nil && info.Tokenizer != "" {
+		if cfg.Format == "markdown" {
+			_, err = fmt.Fprintf(w, "**Tokens (%s):** %d\n", info.Tokenizer, info.Tokens)
+		} else {
+			_, err = fmt.Fprintf(w, "Tokens (%s)=%d\n", info.Tokenizer, info.Tokens)
+		}
+	}
+	if err == nil && len(info.Failed) > 0 {
+		_, err = fmt.Fprintf(w, "Falhas (%d):\n", len(info.Failed))
+		for _, fe := range info.Failed {
+			if err == nil {
+				_, err = fmt.Fprintf(w, "  - %s: %s\n", DisplayPath(fe.Path, cfg), fe.Reason())
+			}
+		}
+	}
+	if err == nil && len(info.Redacted) > 0 {
+		err = writeRedactions(w, cfg, info.Redacted)
+	}
+	if err != nil || cfg.MaxTokens <= 0 {
 		return err
+	}
+	_, err = fmt.Fprintf(w, "Orçamento de tokens: %d de %d\n", info.Budget, cfg.MaxTokens)
+	if err == nil && info.Cut != "" {
+		_, err = fmt.Fprintf(w, "Truncado para caber: %s\n", DisplayPath(info.Cut, cfg))
+	}
+	if err == nil && len(info.Omitted) > 0 {
+		_, err = fmt.Fprintf(w, "Omitidos pelo orçamento (%d):\n", len(info.Omitted))
+		for _, p := range info.Omitted {
+			if err == nil {
+				_, err = fmt.Fprintf(w, "  - %s\n", DisplayPath(p, cfg))
+			}
+		}
+	}
+	return err
+}
+
+// FileExt devolve a extensão de arquivo usual para o formato (modo --split).
+func FileExt(format string) string {
+	switch format {
+	case "markdown", "fenced":
+		return ".md"
+	case "json":
+		return ".json"
+	case "ndjson":
+		return ".ndjson"
+	case "xml":
+		return ".xml"
+	case "html":
+		return ".html"
 	default:
-		_, err := fmt.Fprintf(w, "\n---\nResumo adicional: binários ignorados=%d ; arquivos sensíveis ignorados=%d\n", skippedBin, skippedSec)
+		return ".txt"
+	}
+}
+
+// SplitIndex é o nome (sem extensão) do índice do modo --split, reservado:
+// nenhuma parte recebe esse arquivo.
+const SplitIndex = "_index"
+
+// SplitFiles devolve os nomes de arquivo das partes de nomes names (já como
+// no documento, DisplayPath), com a extensão de FileExt. Nomes que colidem
+// entre si, com o índice ou só na caixa (sistemas de arquivos que não a
+// diferenciam) recebem um sufixo "_2", "_3"... na ordem de names.
+func SplitFiles(names []string, format string) []string {
+	ext := FileExt(format)
+	used := map[string]bool{strings.ToLower(SplitIndex + ext): true}
+	out := make([]string, len(names))
+	for i, n := range names {
+		stem := splitFileName(n)
+		file := stem + ext
+		for k := 2; used[strings.ToLower(file)]; k++ {
+			file = stem + "_" + strconv.Itoa(k) + ext
+		}
+		used[strings.ToLower(file)] = true
+		out[i] = file
+	}
+	return out
+}
+
+// splitFileName transforma o nome da parte em um nome de arquivo seguro.
+func splitFileName(name string) string {
+	r := strings.NewReplacer("/", "__", "\\", "__", ":", "_", " ", "_")
+	return r.Replace(name)
+}
+
+// SplitEntry descreve uma parte gerada no modo --split.
+type SplitEntry struct {
+	Name          string `json:"name"`
+	File          string `json:"file"`
+	Files         int    `json:"files"`
+	Bytes         int64  `json:"bytes"`
+	Tokens        int    `json:"tokens"`
+	SkippedBin    int    `json:"skipped_binary"`
+	SkippedSecret int    `json:"skipped_secret"`
+	Truncated     bool   `json:"truncated"`
+}
+
+// WriteSplitIndex escreve o índice das partes geradas no modo --split.
+func WriteSplitIndex(w io.Writer, cfg cli.Config, parts []SplitEntry) error {
+	paths := strings.Join(displayPaths(cfg.Paths, cfg), ":")
+	switch cfg.Format {
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, p := range parts {
+			if err := enc.Encode(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if parts == nil {
+			parts = []SplitEntry{}
+		}
+		b, err := json.MarshalIndent(parts, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case "xml":
+		var b strings.Builder
+		b.WriteString("<parts")
+		xmlAttr(&b, "generated", util.Now().Format("2006-01-02 15:04:05 -0700"))
+		xmlAttr(&b, "paths", paths)
+		b.WriteString(">\n")
+		for _, p := range parts {
+			b.WriteString("<part")
+			xmlAttr(&b, "name", p.Name)
+			xmlAttr(&b, "file", p.File)
+			xmlAttr(&b, "files", strconv.Itoa(p.Files))
+			xmlAttr(&b, "bytes", strconv.FormatInt(p.Bytes, 10))
+			xmlAttr(&b, "tokens", strconv.Itoa(p.Tokens))
+			xmlAttr(&b, "skipped_binary", strconv.Itoa(p.SkippedBin))
+			xmlAttr(&b, "skipped_secret", strconv.Itoa(p.SkippedSecret))
+			xmlAttr(&b, "truncated", strconv.FormatBool(p.Truncated))
+			b.WriteString("/>\n")
+		}
+		b.WriteString("</parts>\n")
+		_, err := io.WriteString(w, b.String())
 		return err
+	case "html":
+		var b strings.Builder
+		fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"pt-BR\">\n<head>\n<meta charset=\"utf-8\">\n<title>Code Context — Índice</title>\n<style>\n%s</style>\n</head>\n<body>\n<main style=\"margin-left:0\">\n<h1>Code Context — Índice</h1>\n<div class=\"panel\"><dl>\n", htmlStyle)
+		htmlField(&b, "Generated", util.Now().Format("2006-01-02 15:04:05 -0700"))
+		htmlField(&b, "Paths", paths)
+		b.WriteString("</dl></div>\n<table>\n<tr><th>Parte</th><th>Arquivo</th><th>Files</th><th>Bytes</th><th>Tokens</th><th>Binários</th><th>Sensíveis</th></tr>\n")
+		for _, p := range parts {
+			file := html.EscapeString(p.File)
+			if p.Truncated {
+				file += " (truncado)"
+			}
+			fmt.Fprintf(&b, "<tr><td>%s</td><td><a href=\"%s\">%s</a></td><td>%d</td><td>%d</td><td>%d</td><td>%d</td><td>%d</td></tr>\n",
+				html.EscapeString(p.Name), html.EscapeString(p.File), file, p.Files, p.Bytes, p.Tokens, p.SkippedBin, p.SkippedSecret)
+		}
+		b.WriteString("</table>\n</main>\n</body>\n</html>\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	case "markdown", "fenced":
+		if _, err := fmt.Fprintf(w, "# Code Context — Índice\n# Generated: %s\n# Paths: %s\n\n", util.Now().Format("2006-01-02 15:04:05 -0700"), paths); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "| Parte | Arquivo | Files | Bytes | Tokens | Binários | Sensíveis |\n|---|---|---:|---:|---:|---:|---:|\n"); err != nil {
+			return err
+		}
+		for _, p := range parts {
+			file := p.File
+			if p.Truncated {
+				file += " (truncado)"
+			}
+			if _, err := fmt.Fprintf(w, "| %s | [%s](%s) | %d | %d | %d | %d | %d |\n", p.Name, file, p.File, p.Files, p.Bytes, p.Tokens, p.SkippedBin, p.SkippedSecret); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if _, err := fmt.Fprintf(w, "# Code Context — Índice\n# Generated: %s\n# Paths: %s\n\n", util.Now().Format("2006-01-02 15:04:05 -0700"), paths); err != nil {
+			return err
+		}
+		for _, p := range parts {
+			trunc := ""
+			if p.Truncated {
+				trunc = " | TRUNCADO"
+			}
+			if _, err := fmt.Fprintf(w, "%s -> %s | files=%d bytes=%d tokens=%d | binários_ignorados=%d | sensíveis_ignorados=%d%s\n", p.Name, p.File, p.Files, p.Bytes, p.Tokens, p.SkippedBin, p.SkippedSecret, trunc); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 }
 
 func ProcessFiles(ctx context.Context, w io.Writer, files []scan.FileMeta, cfg cli.Config, log *logx.Logger) (*Metrics, error) {
-	// Índices determinísticos já atribuídos
+	return ProcessFilesInfo(ctx, w, files, cfg, DocInfo{}, FooterInfo{}, log)
+}
+
+// ProcessFilesInfo é ProcessFiles com os metadados do cabeçalho (doc) e do
+// rodapé (foot, com os contadores da listagem) que serão escritos em volta
+// dos arquivos: com --max-tokens, o custo deles sai do orçamento antes dos
+// blocos.
+func ProcessFilesInfo(ctx context.Context, w io.Writer, files []scan.FileMeta, cfg cli.Config, doc DocInfo, foot FooterInfo, log *logx.Logger) (*Metrics, error) {
 	type result struct {
-		idx int
-		buf []byte
+		pos int // posição em files: ordem de saída
+		blk block
 		err error
-		sz  int64
+	}
+
+	// Carrega o vocabulário antes dos workers (e falha cedo se inválido)
+	if exactTokens(cfg) {
+		if _, err := tokens.Get(cfg.Tokenizer); err != nil {
+			return nil, err
+		}
 	}
 
 	workers := cfg.Jobs
@@ -77,32 +402,57 @@ func ProcessFiles(ctx context.Context, w io.Writer, files []scan.FileMeta, cfg c
 			workers = 4
 		}
 	}
-	jobs := make(chan scan.FileMeta)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Janela de reordenação: no máximo reorderSlots blocos por worker e
+	// cfg.MaxMemory bytes esperando a vez de serem escritos
+	win := newWindow(workers*reorderSlots, cfg.MaxMemory)
+	stop := context.AfterFunc(ctx, win.close)
+	defer stop()
+
+	type job struct {
+		pos int
+		fm  scan.FileMeta
+	}
+	jobs := make(chan job)
 	out := make(chan result)
 
 	var wg sync.WaitGroup
 	worker := func() {
 		defer wg.Done()
-		for fm := range jobs {
-      var (
-        b      []byte
-        bytesW int64
-        err    error
-      )
-      switch cfg.Format {
-      case "json":
-        b, bytesW, err = renderOneJSON(fm, cfg)
-      case "ndjson":
-        line, _, e := renderOneJSON(fm, cfg)
-        err = e
-        if err == nil {
-          // cada linha termina com \n
-          b = append(line, '\n')
-        }
-      default:
-        b, bytesW, err = renderOneText(fm, cfg)
-      }
-			out <- result{idx: fm.Index, buf: b, err: err, sz: bytesW}
+		for j := range jobs {
+			var (
+				blk block
+				src *source
+				err error
+			)
+			if cfg.MaxTokens > 0 {
+				// o orçamento pode renderizar o bloco de novo, truncado
+				src, err = loadSource(j.fm, cfg)
+			}
+			if err == nil {
+				blk, err = renderOne(j.fm, cfg, src)
+				blk.src = src
+			}
+			var sk *secretSkip
+			if errors.As(err, &sk) {
+				blk, err = block{secret: &sk.d}, nil
+			}
+			if err != nil {
+				fe := &FileError{Path: util.ToSlash(j.fm.Path), Err: err}
+				err = fe
+				if cfg.KeepGoing {
+					blk, err = errorBlock(j.fm, cfg, fe)
+					blk.failed = fe
+				}
+			}
+			select {
+			case out <- result{pos: j.pos, blk: blk, err: err}:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 	wg.Add(workers)
@@ -110,72 +460,271 @@ func ProcessFiles(ctx context.Context, w io.Writer, files []scan.FileMeta, cfg c
 		go worker()
 	}
 
+	// Despacho na ordem de saída, reservando lugar na janela por estimativa
+	est := make([]int64, len(files))
 	go func() {
-		for _, fm := range files {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(out)
+		}()
+		for i, fm := range files {
+			est[i] = estimateBlock(fm)
+			if !win.acquire(est[i]) {
+				return
+			}
 			select {
 			case <-ctx.Done():
 				return
-			case jobs <- fm:
+			case jobs <- job{pos: i, fm: fm}:
 			}
 		}
-		close(jobs)
-		wg.Wait()
-		close(out)
 	}()
 
-	// Reunião dos resultados por índice para impressão ordenada
-	results := make([][]byte, len(files))
-	var totalBytes int64
+	// Escrita em ordem assim que o próximo bloco fica pronto
+	var (
+		bg       = newBudget(cfg, files, doc, foot)
+		pending  = map[int]result{}
+		next     int
+		first    = true
+		m        = &Metrics{Tokenizer: tokenizerName(cfg)}
+		runErr error
+	)
+	if bg != nil && log != nil && bg.head0+bg.foot0 > bg.max {
+		log.Warn("Cabeçalho e rodapé do documento já usam %d tokens, acima de --max-tokens=%d.", bg.head0+bg.foot0, bg.max)
+	}
+	emit := func(r result) error {
+		blk := r.blk
+		if blk.secret != nil {
+			m.SecretSkips = append(m.SecretSkips, SecretSkip{Path: files[r.pos].Path, Decision: *blk.secret})
+			return nil
+		}
+		if blk.failed != nil {
+			m.Failed = append(m.Failed, blk.failed)
+			if log != nil {
+				log.Warn("Falha em %s (--keep-going): %s", blk.failed.Path, blk.failed.Reason())
+			}
+		} else if bg != nil {
+			var err error
+			if blk, err = bg.admit(r.pos, files[r.pos], cfg, blk); err != nil {
+				return err
+			}
+		}
+		if blk.buf == nil {
+			return nil
+		}
+		if cfg.Format == "json" && !first {
+			if _, err := io.WriteString(w, ",\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(blk.buf); err != nil {
+			return err
+		}
+		first = false
+		m.Written = append(m.Written, files[r.pos])
+		if blk.failed == nil {
+			m.Files++
+		}
+		if len(blk.redactions) > 0 {
+			m.Redacted = append(m.Redacted, FileRedactions{Path: util.ToSlash(files[r.pos].Path), Items: blk.redactions})
+		}
+		m.Bytes += blk.written
+		m.Tokens += blk.content
+		return nil
+	}
 	for r := range out {
 		if r.err != nil {
-			return nil, r.err
-		}
-		results[r.idx] = r.buf
-		totalBytes += r.sz
-	}
-
-	if cfg.Format == "json" {
-    for i := range results {
-      if results[i] == nil {
-        continue
-      }
-      if i > 0 {
-        if _, err := io.WriteString(w, ",\n"); err != nil {
-          return nil, err
-        }
-      }
-      if _, err := w.Write(results[i]); err != nil {
-        return nil, err
-      }
-    }
-  } else {
-    for i := range results {
-      if results[i] != nil {
-        if _, err := w.Write(results[i]); err != nil {
-          return nil, err
-        }
-      }
-    }
-  }
-	return &Metrics{Files: len(files), Bytes: totalBytes}, nil
+			runErr = r.err
+			break
+		}
+		// est[r.pos] foi escrito antes do envio do job: leitura segura
+		win.adjust(r.blk.size() - est[r.pos])
+		pending[r.pos] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			err := emit(r)
+			win.release(r.blk.size())
+			if err != nil {
+				runErr = err
+				break
+			}
+		}
+		if runErr != nil {
+			break
+		}
+	}
+	if runErr != nil {
+		cancel()
+		for range out {
+		}
+		return nil, runErr
+	}
+	if next < len(files) {
+		return nil, ctx.Err()
+	}
+	if log != nil {
+		log.Debug("Janela de reordenação: pico de %d bytes em memória", win.peakBytes())
+	}
+	if bg != nil {
+		m.Budget, m.Omitted, m.Cut = bg.used(), bg.omitted, bg.cut
+	}
+	return m, nil
 }
 
-func renderOneText(fm scan.FileMeta, cfg cli.Config) ([]byte, int64, error) {
-	pathOut := util.ToSlash(fm.Path)
-	size := fm.Size
-	hash, _ := util.Sha256Short8(fm.Path)
-	lines := countLines(fm.Path)
+// reorderSlots é quantos blocos por worker podem esperar pela vez de serem
+// escritos; --max-memory limita o mesmo conjunto em bytes.
+const reorderSlots = 4
 
-	var b strings.Builder
-  headerFor(&b, cfg, pathOut, size, hash, lines)
+// estimateBlock estima os bytes do bloco de fm antes da renderização: o
+// conteúdo mais uma folga para o cabeçalho. A reserva é corrigida pelo
+// tamanho real quando o bloco fica pronto.
+func estimateBlock(fm scan.FileMeta) int64 {
+	return fm.Size + 512
+}
+
+// block é o trecho renderizado de um arquivo. buf nil significa "nada a
+// mostrar".
+type block struct {
+	buf     []byte
+	written int64 // bytes de conteúdo (corpo e diff)
+	tokens  int   // tokens do bloco inteiro (só com --max-tokens)
+	content int   // tokens do conteúdo: o Tokens do cabeçalho do arquivo
+	src     *source // --max-tokens: a leitura que truncateToFit renderiza de novo
+
+	failed     *FileError        // registro de falha (--keep-going) no lugar do arquivo
+	secret     *filters.Decision // descartado por segredo no conteúdo (sem buf)
+	redactions []Redaction       // --redact
+}
+
+// size devolve os bytes que b ocupa na janela de reordenação.
+func (b block) size() int64 { return int64(len(b.buf)) + b.src.size() }
+
+// renderOne produz o bloco de um arquivo no formato de cfg, a partir de src
+// quando já lido (veja source).
+func renderOne(fm scan.FileMeta, cfg cli.Config, src *source) (block, error) {
+	switch cfg.Format {
+	case "json":
+		return renderOneJSON(fm, cfg, src)
+	case "ndjson":
+		blk, err := renderOneJSON(fm, cfg, src)
+		if err != nil || blk.buf == nil {
+			return block{}, err
+		}
+		// cada linha termina com \n
+		blk.buf, blk.written = append(blk.buf, '\n'), 0
+		return blk, nil
+	case "xml":
+		return renderOneXML(fm, cfg, src)
+	case "html":
+		return renderOneHTML(fm, cfg, src)
+	case "template":
+		return renderOneTemplate(fm, cfg, src)
+	default:
+		return renderOneText(fm, cfg, src)
+	}
+}
+
+func renderOneText(fm scan.FileMeta, cfg cli.Config, src *source) (block, error) {
+	diff, err := src.diffFor(fm, cfg)
+	if err != nil {
+		return block{}, err
+	}
+	if cfg.DiffOnly && diff == "" {
+		return block{}, nil // sem alterações: nada a mostrar
+	}
+
+	// Uma única leitura: hash, linhas e corpo do mesmo conteúdo
+	rd := newRedactor(fm.Path, cfg)
+	snap, err := readSnapshot(fm, cfg, src, contentRedactor(rd, cfg), !cfg.IndexOnly && !cfg.DiffOnly, cfg.IndexOnly)
+	if err != nil {
+		return block{}, err
+	}
+	diff = anonymizer(cfg).Text(rd.applyString(diff, true))
+	h := fileHead{path: DisplayPath(fm.Path, cfg), size: snap.size, hash: snap.hash, lines: snap.lines}
+	body, written := snap.body, snap.written
+	switch {
+	case cfg.DiffOnly:
+		h.lang = "diff"
+		var sb strings.Builder
+		written, _ = writeLines(&sb, strings.NewReader(diff), cfg.MaxLines, cfg.MaxCols)
+		body = sb.String()
+		h.tokens = countTokens(cfg, body)
+	case cfg.IndexOnly:
+		h.lang = langFor(fm.Path, snap.full)
+		h.tokens = countTokens(cfg, snap.full)
+	default:
+		h.lang = langFor(fm.Path, body)
+		h.tokens = countTokens(cfg, body)
+	}
+	// o caminho também vai dentro do bloco em fenced
+	h.fence = tmpl.Fence(h.path + "\n" + body)
 
-  written := 0
-  if !cfg.IndexOnly {
-    written, _ = writeBody(&b, fm.Path, cfg.MaxLines, cfg.MaxCols)
+	var b strings.Builder
+  headerFor(&b, cfg, h)
+	head := b.Len()
+  b.WriteString(body)
+	mark := b.Len()
+  footerFor(&b, cfg, h.fence)
+  if cfg.WithDiff && !cfg.DiffOnly && diff != "" {
+    written += diffBlock(&b, cfg, diff)
   }
+	out := b.String()
+	// Os tokens do bloco somam as partes, para não tokenizar o corpo de novo
+	tok := blockTokens(cfg, out[:head]) + blockTokens(cfg, out[mark:])
+	if tok > 0 && !cfg.IndexOnly {
+		tok += h.tokens
+	}
+	return block{buf: []byte(out), written: int64(written), tokens: tok, content: h.tokens, redactions: rd.redactions()}, nil
+}
 
-  footerFor(&b, cfg)
-	return []byte(b.String()), int64(written), nil
+// diffFor calcula os hunks de fm quando --with-diff/--diff-only estão ativos.
+// Os hunks trazem linhas que o corpo não tem (as removidas), então passam
+// pela mesma varredura de conteúdo: um segredo ali descarta o arquivo
+// (secretSkip), a menos que --redact vá substituí-lo.
+func diffFor(fm scan.FileMeta, cfg cli.Config) (string, error) {
+	if !cfg.WithDiff && !cfg.DiffOnly {
+		return "", nil
+	}
+	d, err := gitx.Diff(fm.Path, scan.ChangeSpec(cfg), cfg.DiffContext)
+	if err != nil {
+		return "", fmt.Errorf("diff (--with-diff/--diff-only requerem repositório git): %w", err)
+	}
+	if d != "" && cfg.SecretsStrict && !cfg.Redact {
+		if dec := filters.DecideContent(fm.Path, []byte(d), cfg); !dec.Include {
+			return "", &secretSkip{d: dec}
+		}
+	}
+	return d, nil
+}
+
+// diffBlock anexa o diff após o conteúdo do arquivo (--with-diff).
+func diffBlock(b *strings.Builder, cfg cli.Config, diff string) int {
+	r := strings.NewReader(diff)
+	switch cfg.Format {
+	case "markdown":
+		fence := tmpl.Fence(diff)
+		b.WriteString("\n**Diff:**\n\n" + fence + "diff\n")
+		n, _ := writeLines(b, r, cfg.MaxLines, cfg.MaxCols)
+		b.WriteString(fence + "\n")
+		return n
+	case "fenced":
+		fence := tmpl.Fence(diff)
+		b.WriteString("\n" + fence + "diff\n")
+		n, _ := writeLines(b, r, cfg.MaxLines, cfg.MaxCols)
+		b.WriteString(fence + "\n")
+		return n
+	default:
+		b.WriteString("------------------------------------ DIFF --------------------------------------\n")
+		n, _ := writeLines(b, r, cfg.MaxLines, cfg.MaxCols)
+		b.WriteString("\n")
+		return n
+	}
 }
 
 type jsonRec struct {
@@ -183,101 +732,159 @@ type jsonRec struct {
   Size    int64  `json:"size"`
   Hash    string `json:"hash"`
   Lines   int    `json:"lines"`
+  Tokens  *int   `json:"tokens,omitempty"` // só com contagem exata (exactTokens)
   MTime   int64  `json:"mtime"`
   Ext     string `json:"ext"`
   Index   int    `json:"index"`
+  Score   *float64 `json:"score,omitempty"`
   Content string `json:"content,omitempty"`
+  Diff    string `json:"diff,omitempty"`
+  Error   string `json:"error,omitempty"` // --keep-going: falha ao ler o arquivo
+  Redactions []Redaction `json:"redactions,omitempty"` // --redact
+
+  count             int  // tokens do conteúdo, exatos ou estimados (o Tokens do bloco)
+  cutLines, cutCols bool // conteúdo cortado por --max-lines/--max-cols (-F template)
+}
+
+func renderOneJSON(fm scan.FileMeta, cfg cli.Config, src *source) (block, error) {
+  rec, written, err := fileRec(fm, cfg, src)
+  if err != nil || rec == nil {
+    return block{}, err
+  }
+  b, err := json.Marshal(rec)
+  if err != nil {
+    return block{}, err
+  }
+  return block{buf: b, written: int64(written), tokens: blockTokens(cfg, string(b)), content: rec.count, redactions: rec.Redactions}, nil
 }
 
-func renderOneJSON(fm scan.FileMeta, cfg cli.Config) ([]byte, int64, error) {
-  hash, _ := util.Sha256Short8(fm.Path)
+// fileRec lê fm e monta o registro dos formatos estruturados (json, ndjson,
+// xml) e os bytes de conteúdo emitidos. Registro nil: nada a mostrar.
+func fileRec(fm scan.FileMeta, cfg cli.Config, src *source) (*jsonRec, int, error) {
+  diff, err := src.diffFor(fm, cfg)
+  if err != nil {
+    return nil, 0, err
+  }
+  if cfg.DiffOnly && diff == "" {
+    return nil, 0, nil
+  }
+  rd := newRedactor(fm.Path, cfg)
+  snap, err := readSnapshot(fm, cfg, src, contentRedactor(rd, cfg), !cfg.IndexOnly && !cfg.DiffOnly, cfg.IndexOnly)
+  if err != nil {
+    return nil, 0, err
+  }
+  diff = anonymizer(cfg).Text(rd.applyString(diff, true))
   rec := jsonRec{
-    Path:  util.ToSlash(fm.Path),
-    Size:  fm.Size,
-    Hash:  hash,
-    Lines: countLines(fm.Path),
+    Path:  DisplayPath(fm.Path, cfg),
+    Size:  snap.size,
+    Hash:  snap.hash,
+    Lines: snap.lines,
     MTime: fm.MTime,
     Ext:   strings.TrimPrefix(strings.ToLower(filepath.Ext(fm.Path)), "."),
     Index: fm.Index,
   }
+  if cfg.Order == "relevance" {
+    sc := math.Round(fm.Score*10000) / 10000
+    rec.Score = &sc
+  }
   var written int
-  if !cfg.IndexOnly {
+  if !cfg.IndexOnly && !cfg.DiffOnly {
+    written = snap.written
+    rec.Content = snap.body
+    rec.cutLines = cfg.MaxLines > 0 && snap.lines > cfg.MaxLines
+    rec.cutCols = snap.cutCols
+    rec.count = countTokens(cfg, rec.Content)
+  }
+  if diff != "" {
     var sb strings.Builder
-    w, _ := writeBody(&sb, fm.Path, cfg.MaxLines, cfg.MaxCols)
-    written = w
-    rec.Content = sb.String()
+    w, _ := writeLines(&sb, strings.NewReader(diff), cfg.MaxLines, cfg.MaxCols)
+    written += w
+    rec.Diff = sb.String()
+    if cfg.DiffOnly {
+      rec.count = countTokens(cfg, rec.Diff)
+    }
   }
-  b, err := json.Marshal(rec)
-  return b, int64(written), err
+  if cfg.IndexOnly {
+    rec.count = countTokens(cfg, snap.full)
+  }
+  if exactTokens(cfg) {
+    n := rec.count
+    rec.Tokens = &n
+  }
+  rec.Redactions = rd.redactions()
+  return &rec, written, nil
 }
 
-func headerFor(b *strings.Builder, cfg cli.Config, pathOut string, size int64, hash string, lines int) {
+// fileHead reúne os metadados do cabeçalho de um arquivo.
+type fileHead struct {
+	path   string
+	lang   string // linguagem do bloco (langFor, ou "diff" em --diff-only)
+	fence  string // cerca do bloco, maior que qualquer sequência de crases do corpo
+	size   int64
+	hash   string
+	lines  int
+	tokens int // tokens do conteúdo emitido (do arquivo inteiro em --index-only); no cabeçalho só se exatos
+}
+
+// headerFor escreve o cabeçalho de um arquivo; markdown e fenced abrem o
+// bloco com h.fence e h.lang.
+func headerFor(b *strings.Builder, cfg cli.Config, h fileHead) {
 	switch cfg.Format {
 	case "markdown":
-		fmt.Fprintf(b, "\n## %s\n\n", pathOut)
-		fmt.Fprintf(b, " - **Size:** %d bytes\n", size)
-		fmt.Fprintf(b, " - **Hash:** %s\n", hash)
-		fmt.Fprintf(b, " - **Lines:** %d\n\n", lines)
-		b.WriteString("```\n")
+		fmt.Fprintf(b, "\n## %s\n\n", h.path)
+		fmt.Fprintf(b, " - **Size:** %d bytes\n", h.size)
+		fmt.Fprintf(b, " - **Hash:** %s\n", h.hash)
+		fmt.Fprintf(b, " - **Lines:** %d\n", h.lines)
+		if exactTokens(cfg) {
+			fmt.Fprintf(b, " - **Tokens:** %d\n", h.tokens)
+		}
+		fmt.Fprintf(b, "\n%s%s\n", h.fence, h.lang)
 	case "fenced":
-		fmt.Fprintf(b, "\n```%s\n", fencedLang(pathOut))
-		fmt.Fprintf(b, "# File: %s\n", pathOut)
-		fmt.Fprintf(b, "# Size: %d bytes | Hash: %s | Lines: %d\n", size, hash, lines)
+		fmt.Fprintf(b, "\n%s%s\n", h.fence, h.lang)
+		fmt.Fprintf(b, "# File: %s\n", h.path)
+		fmt.Fprintf(b, "# Size: %d bytes | Hash: %s | Lines: %d", h.size, h.hash, h.lines)
+		if exactTokens(cfg) {
+			fmt.Fprintf(b, " | Tokens: %d", h.tokens)
+		}
+		b.WriteString("\n")
 	default:
 		b.WriteString("\n================================================================================\n")
-		fmt.Fprintf(b, "FILE: %s\n", pathOut)
-		fmt.Fprintf(b, "SIZE: %d bytes | HASH: %s | LINES: %d\n", size, hash, lines)
+		fmt.Fprintf(b, "FILE: %s\n", h.path)
+		fmt.Fprintf(b, "SIZE: %d bytes | HASH: %s | LINES: %d", h.size, h.hash, h.lines)
+		if exactTokens(cfg) {
+			fmt.Fprintf(b, " | TOKENS: %d", h.tokens)
+		}
+		b.WriteString("\n")
 		b.WriteString("--------------------------------------------------------------------------------\n")
 	}
 }
 
-func footerFor(b *strings.Builder, cfg cli.Config) {
+func footerFor(b *strings.Builder, cfg cli.Config, fence string) {
 	switch cfg.Format {
 	case "markdown", "fenced":
-		b.WriteString("\n```\n")
+		b.WriteString("\n" + fence + "\n")
 	default:
 		b.WriteString("\n")
 	}
 }
 
-func fencedLang(pathOut string) string {
-	ext := strings.ToLower(filepath.Ext(pathOut))
-	if len(ext) > 0 && ext[0] == '.' {
-		return ext[1:]
-	}
-	return ext
-}
-
-func writeBody(b *strings.Builder, path string, maxLines, maxCols int) (int, error) {
-	f, err := util.OpenRead(path)
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-
-	sc := bufio.NewScanner(f)
-	// aumenta limite de buffer para linhas longas
-	const maxCap = 4 * 1024 * 1024
-	buf := make([]byte, 0, 64*1024)
-	sc.Buffer(buf, maxCap)
-
-	lines := 0
-	written := 0
-	for sc.Scan() {
-		line := sc.Text()
-		if maxCols > 0 && len([]rune(line)) > maxCols {
-			line = truncateCols(line, maxCols)
-		}
-		b.WriteString(line)
-		b.WriteByte('\n')
-		written += len(line) + 1
-		lines++
-		if maxLines > 0 && lines >= maxLines {
-			fmt.Fprintf(b, "\n[... truncado em %d linhas ...]\n", maxLines)
-			break
+// writeLines copia r para b aplicando os limites de linhas e colunas.
+func writeLines(b *strings.Builder, r io.Reader, maxLines, maxCols int) (int, error) {
+	br := bufio.NewReader(r)
+	lw := lineWriter{b: b, maxLines: maxLines, maxCols: maxCols}
+	for !lw.done {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			lw.add(line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return lw.written, err
 		}
 	}
-	return written, nil
+	return lw.written, nil
 }
 
 func truncateCols(s string, max int) string {
@@ -288,17 +895,3 @@ func truncateCols(s string, max int) string {
 	r = r[:max]
 	return string(r) + "... [truncated]"
 }
-
-func countLines(path string) int {
-	f, err := os.Open(path)
-	if err != nil {
-		return 0
-	}
-	defer f.Close()
-	sc := bufio.NewScanner(f)
-	lines := 0
-	for sc.Scan() {
-		lines++
-	}
-	return lines
-}
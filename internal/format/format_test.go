@@ -38,3 +38,12 @@ func TestFormatMarkdownAndFenced(t *testing.T) {
 		t.Fatalf("fenced deveria conter ```go; got:\n%s", out)
 	}
 }
+
+func TestSplitFilesAvoidCollisions(t *testing.T) {
+	names := []string{"_index", "a/b", "a__b", "A__B", "svc"}
+	got := format.SplitFiles(names, "markdown")
+	want := []string{"_index_2.md", "a__b.md", "a__b_2.md", "A__B_3.md", "svc.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,296 @@
+package format
+
+import (
+	"html"
+	"strings"
+)
+
+// Realce de sintaxe do formato html: um lexer simples guiado por syntax
+// (comentários, strings, números e palavras-chave), suficiente para leitura
+// sem depender de assets externos. Linguagens desconhecidas saem sem realce.
+
+// syntax descreve o léxico de uma linguagem.
+type syntax struct {
+	line     []string    // início de comentário de linha
+	block    [][2]string // delimitadores de comentário de bloco
+	quotes   string      // delimitadores de string de uma linha (com escape \)
+	raw      []string    // delimitadores de string que atravessam linhas
+	keywords map[string]bool
+	fold     bool // palavras-chave sem diferenciar caixa (SQL)
+}
+
+func words(s string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	cBlock = [][2]string{{"/*", "*/"}}
+
+	syntaxGo = &syntax{line: []string{"//"}, block: cBlock, quotes: `"'`, raw: []string{"`"}, keywords: words(`
+		break case chan const continue default defer else fallthrough for func go goto if import
+		interface map package range return select struct switch type var
+		nil true false iota any bool byte error int int8 int16 int32 int64 uint uint8 uint16
+		uint32 uint64 uintptr float32 float64 string rune append len cap make new panic recover`)}
+
+	syntaxC = &syntax{line: []string{"//"}, block: cBlock, quotes: `"'`, keywords: words(`
+		auto break case char const continue default do double else enum extern float for goto if
+		inline int long register return short signed sizeof static struct switch typedef union
+		unsigned void volatile while bool true false NULL nullptr class namespace template
+		typename public private protected virtual override new delete this using try catch throw
+		const_cast static_cast dynamic_cast reinterpret_cast include define ifdef ifndef endif`)}
+
+	syntaxJava = &syntax{line: []string{"//"}, block: cBlock, quotes: `"'`, raw: []string{`"""`}, keywords: words(`
+		abstract boolean break byte case catch char class const continue default do double else
+		enum extends final finally float for if implements import instanceof int interface long
+		native new null package private protected public return short static super switch
+		synchronized this throw throws transient try void volatile while true false var val fun
+		object when is in as override open data sealed internal companion lateinit suspend
+		namespace using struct readonly async await string bool`)}
+
+	syntaxJS = &syntax{line: []string{"//"}, block: cBlock, quotes: `"'`, raw: []string{"`"}, keywords: words(`
+		async await break case catch class const continue debugger default delete do else export
+		extends finally for from function if import in instanceof let new null of return static
+		super switch this throw try typeof undefined var void while with yield true false
+		interface type enum implements private protected public readonly declare namespace as
+		keyof any number string boolean never unknown`)}
+
+	syntaxRust = &syntax{line: []string{"//"}, block: cBlock, quotes: `"`, keywords: words(`
+		as async await break const continue crate dyn else enum extern false fn for if impl in let
+		loop match mod move mut pub ref return self Self static struct super trait true type
+		unsafe use where while Some None Ok Err Option Result Box Vec String`)}
+
+	syntaxPython = &syntax{line: []string{"#"}, quotes: `"'`, raw: []string{`"""`, `'''`}, keywords: words(`
+		and as assert async await break class continue def del elif else except False finally
+		for from global if import in is lambda None nonlocal not or pass raise return True try
+		while with yield self print`)}
+
+	syntaxRuby = &syntax{line: []string{"#"}, quotes: `"'`, keywords: words(`
+		alias and begin break case class def do else elsif end ensure false for if in
+		module next nil not or redo rescue retry return self super then true undef unless until
+		when while yield require attr_reader attr_accessor`)}
+
+	syntaxShell = &syntax{line: []string{"#"}, quotes: `"'`, keywords: words(`
+		if then else elif fi case esac for while until do done in function return local export
+		readonly set unset shift exit echo source declare`)}
+
+	syntaxSQL = &syntax{line: []string{"--"}, block: cBlock, quotes: `'"`, fold: true, keywords: words(`
+		select from where and or not insert into values update set delete create table alter
+		drop index view join left right inner outer on group by order having limit offset as
+		distinct union all null is in like between case when then else end primary key foreign
+		references default begin commit rollback`)}
+
+	syntaxConf = &syntax{line: []string{"#"}, quotes: `"'`, keywords: words(`true false null yes no on off`)}
+
+	syntaxJSON = &syntax{quotes: `"`, keywords: words(`true false null`)}
+
+	syntaxMarkup = &syntax{block: [][2]string{{"<!--", "-->"}}, quotes: `"`}
+)
+
+// syntaxByLang associa as tags de langFor aos léxicos.
+var syntaxByLang = map[string]*syntax{
+	"go": syntaxGo,
+	"c":  syntaxC, "cpp": syntaxC, "objectivec": syntaxC,
+	"java": syntaxJava, "kotlin": syntaxJava, "scala": syntaxJava, "groovy": syntaxJava,
+	"csharp": syntaxJava, "swift": syntaxJava, "dart": syntaxJava,
+	"javascript": syntaxJS, "jsx": syntaxJS, "typescript": syntaxJS, "tsx": syntaxJS,
+	"rust":   syntaxRust,
+	"python": syntaxPython, "starlark": syntaxPython,
+	"ruby": syntaxRuby,
+	"bash": syntaxShell, "sh": syntaxShell, "zsh": syntaxShell, "makefile": syntaxShell, "dockerfile": syntaxShell,
+	"sql":  syntaxSQL,
+	"yaml": syntaxConf, "toml": syntaxConf, "ini": syntaxConf, "properties": syntaxConf, "dotenv": syntaxConf,
+	"json": syntaxJSON, "jsonc": syntaxJSON,
+	"html": syntaxMarkup, "xml": syntaxMarkup, "vue": syntaxMarkup, "svelte": syntaxMarkup,
+}
+
+// syntaxFor devolve o léxico do arquivo path (nil se desconhecido); body
+// serve ao shebang (veja langFor).
+func syntaxFor(path, body string) *syntax {
+	return syntaxByLang[langFor(path, body)]
+}
+
+// highlight devolve src em HTML, uma linha por <span class="l"> (a
+// numeração vem do CSS). As classes de token (k, s, c, n) nunca atravessam
+// uma quebra de linha: são fechadas e reabertas em cada linha.
+func highlight(src string, syn *syntax) string {
+	var b strings.Builder
+	b.Grow(len(src) + len(src)/4)
+	ls := &lineSpans{b: &b}
+	if syn == nil {
+		ls.text("", src)
+	} else {
+		lex(src, syn, ls.text)
+	}
+	ls.end()
+	return b.String()
+}
+
+// highlightDiff realça um diff unificado por linha: inclusões, remoções e
+// cabeçalhos de hunk.
+func highlightDiff(diff string) string {
+	var b strings.Builder
+	ls := &lineSpans{b: &b}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		class := ""
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			class = "h"
+		case strings.HasPrefix(line, "+"):
+			class = "a"
+		case strings.HasPrefix(line, "-"):
+			class = "d"
+		}
+		ls.text(class, line)
+	}
+	ls.end()
+	return b.String()
+}
+
+// lineSpans escreve trechos classificados partindo-os nas quebras de linha.
+type lineSpans struct {
+	b      *strings.Builder
+	inLine bool
+}
+
+func (ls *lineSpans) text(class, s string) {
+	for s != "" {
+		seg, rest, nl := strings.Cut(s, "\n")
+		if !ls.inLine {
+			ls.b.WriteString(`<span class="l">`)
+			ls.inLine = true
+		}
+		if seg != "" {
+			if class != "" {
+				ls.b.WriteString(`<span class="` + class + `">`)
+			}
+			ls.b.WriteString(html.EscapeString(seg))
+			if class != "" {
+				ls.b.WriteString("</span>")
+			}
+		}
+		if !nl {
+			return
+		}
+		ls.b.WriteString("</span>\n")
+		ls.inLine = false
+		s = rest
+	}
+}
+
+// end fecha a última linha se ela não terminou em quebra.
+func (ls *lineSpans) end() {
+	if ls.inLine {
+		ls.b.WriteString("</span>")
+		ls.inLine = false
+	}
+}
+
+// lex percorre src chamando emit para cada trecho, com a classe do token
+// ("" para texto comum).
+func lex(src string, syn *syntax, emit func(class, s string)) {
+	plain := 0 // início do texto comum ainda não emitido
+	flush := func(i int) {
+		if i > plain {
+			emit("", src[plain:i])
+		}
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		if tok, class := syn.token(src, i); tok > 0 {
+			flush(i)
+			emit(class, src[i:i+tok])
+			i += tok
+			plain = i
+			continue
+		}
+		if isWordStart(c) && (i == 0 || !isWordChar(src[i-1])) {
+			j := i + 1
+			for j < len(src) && isWordChar(src[j]) {
+				j++
+			}
+			w := src[i:j]
+			if syn.fold {
+				w = strings.ToLower(w)
+			}
+			if syn.keywords[w] {
+				flush(i)
+				emit("k", src[i:j])
+				plain = j
+			}
+			i = j
+			continue
+		}
+		if c >= '0' && c <= '9' && (i == 0 || !isWordChar(src[i-1])) {
+			j := i + 1
+			for j < len(src) && (isWordChar(src[j]) || src[j] == '.') {
+				j++
+			}
+			flush(i)
+			emit("n", src[i:j])
+			i, plain = j, j
+			continue
+		}
+		i++
+	}
+	flush(len(src))
+}
+
+// token reconhece em src[i:] um comentário ou string e devolve o tamanho e a
+// classe (0 se não há).
+func (syn *syntax) token(src string, i int) (int, string) {
+	rest := src[i:]
+	for _, p := range syn.line {
+		if strings.HasPrefix(rest, p) {
+			if j := strings.IndexByte(rest, '\n'); j >= 0 {
+				return j, "c"
+			}
+			return len(rest), "c"
+		}
+	}
+	for _, d := range syn.block {
+		if strings.HasPrefix(rest, d[0]) {
+			return closing(rest, len(d[0]), d[1]), "c"
+		}
+	}
+	for _, q := range syn.raw {
+		if strings.HasPrefix(rest, q) {
+			return closing(rest, len(q), q), "s"
+		}
+	}
+	if strings.IndexByte(syn.quotes, rest[0]) >= 0 {
+		q := rest[0]
+		for j := 1; j < len(rest); j++ {
+			switch rest[j] {
+			case '\\':
+				j++
+			case q:
+				return j + 1, "s"
+			case '\n':
+				return j, "s" // string sem fim: termina na linha
+			}
+		}
+		return len(rest), "s"
+	}
+	return 0, ""
+}
+
+// closing devolve o tamanho de s até o fim do delimitador end procurado a
+// partir de from (s inteiro se não houver).
+func closing(s string, from int, end string) int {
+	if j := strings.Index(s[from:], end); j >= 0 {
+		return from + j + len(end)
+	}
+	return len(s)
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,214 @@
+package format
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// Formato html (-F html): um único arquivo, sem assets externos, para anexar
+// a revisões. O cabeçalho abre o documento, cada arquivo vira uma <section>
+// com âncora "f<index>", números de linha (CSS) e realce de sintaxe
+// (highlight.go); o rodapé traz o painel de resumo e a árvore lateral, que só
+// pode ser montada depois que os arquivos emitidos são conhecidos (o CSS a
+// fixa à esquerda).
+
+const htmlStyle = `:root{--bg:#fff;--fg:#1f2328;--mut:#656d76;--bd:#d0d7de;--pane:#f6f8fa;--k:#cf222e;--s:#0a3069;--c:#6e7781;--n:#0550ae;--a:#e6ffec;--d:#ffebe9;--h:#ddf4ff}
+@media (prefers-color-scheme:dark){:root{--bg:#0d1117;--fg:#e6edf3;--mut:#8d96a0;--bd:#30363d;--pane:#161b22;--k:#ff7b72;--s:#a5d6ff;--c:#8b949e;--n:#79c0ff;--a:#12261e;--d:#25171c;--h:#121d2f}}
+*{box-sizing:border-box}
+body{margin:0;background:var(--bg);color:var(--fg);font:14px/1.5 system-ui,sans-serif}
+main{margin-left:20rem;padding:1rem 2rem;min-width:0}
+nav{position:fixed;top:0;left:0;bottom:0;width:20rem;overflow:auto;padding:1rem;background:var(--pane);border-right:1px solid var(--bd);font-size:13px}
+nav ul{list-style:none;margin:0;padding-left:1rem}
+nav>ul{padding-left:0}
+nav summary{cursor:pointer;color:var(--mut)}
+nav a{color:inherit;text-decoration:none;word-break:break-all}
+nav a:hover{text-decoration:underline}
+a.failed{color:var(--k)}
+.panel{background:var(--pane);border:1px solid var(--bd);border-radius:6px;padding:.5rem 1rem}
+.panel dl{display:grid;grid-template-columns:max-content 1fr;gap:.1rem 1rem;margin:.5rem 0}
+.panel dt{color:var(--mut)}
+.panel dd{margin:0;word-break:break-all}
+section.file{margin:2rem 0}
+section.file h2{font-size:1rem;font-family:ui-monospace,monospace;margin:0 0 .25rem}
+section.file h2 a{color:inherit;text-decoration:none}
+.meta{color:var(--mut);margin:0 0 .5rem;font-size:12px}
+.error{color:var(--k)}
+pre.code{margin:0;padding:.5rem 0;overflow:auto;background:var(--pane);border:1px solid var(--bd);border-radius:6px;font:12px/1.45 ui-monospace,SFMono-Regular,Menlo,monospace;counter-reset:l}
+pre.code .l{counter-increment:l}
+pre.code .l::before{content:counter(l);display:inline-block;width:3.5rem;padding-right:1rem;text-align:right;color:var(--mut);user-select:none}
+.k{color:var(--k)}.s{color:var(--s)}.c{color:var(--c);font-style:italic}.n{color:var(--n)}
+.a{background:var(--a)}.d{background:var(--d)}.h{background:var(--h);color:var(--mut)}
+`
+
+// writeHTMLHeader abre o documento e escreve o painel do cabeçalho.
+func writeHTMLHeader(w io.Writer, cfg cli.Config, info DocInfo) error {
+	var b strings.Builder
+	roots := make([]string, len(cfg.Paths))
+	for i, r := range cfg.Paths {
+		roots[i] = DisplayPath(r, cfg)
+	}
+	paths := strings.Join(roots, ":")
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"pt-BR\">\n<head>\n<meta charset=\"utf-8\">\n<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n<title>Code Context — %s</title>\n<style>\n%s</style>\n</head>\n<body>\n<main>\n", html.EscapeString(paths), htmlStyle)
+	b.WriteString("<h1>Code Context</h1>\n<div class=\"panel\" id=\"header\"><dl>\n")
+	htmlField(&b, "Generated", util.Now().Format("2006-01-02 15:04:05 -0700"))
+	htmlField(&b, "Paths", paths)
+	if cfg.ExtCSV != "" {
+		htmlField(&b, "Extensions", cfg.ExtCSV)
+	}
+	if cfg.Depth > 0 {
+		htmlField(&b, "Max Depth", strconv.Itoa(cfg.Depth))
+	}
+	if cfg.PolicyHash != "" {
+		files := make([]string, len(cfg.PolicyFiles))
+		for i, f := range cfg.PolicyFiles {
+			files[i] = DisplayPath(f, cfg)
+		}
+		htmlField(&b, "Policy", cfg.PolicyHash+" ("+strings.Join(files, ", ")+")")
+	}
+	if info.Revision != "" {
+		htmlField(&b, "Revision", info.Revision)
+	}
+	if info.Changes != "" {
+		htmlField(&b, "Changes", info.Changes)
+	}
+	if len(info.Deleted) > 0 {
+		del := make([]string, len(info.Deleted))
+		for i, d := range info.Deleted {
+			del[i] = DisplayPath(d, cfg)
+		}
+		htmlField(&b, fmt.Sprintf("Deleted (%d)", len(del)), strings.Join(del, ", "))
+	}
+	b.WriteString("</dl></div>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHTMLFooter escreve o painel de resumo, a árvore lateral e fecha o
+// documento.
+func writeHTMLFooter(w io.Writer, cfg cli.Config, info FooterInfo) error {
+	var b strings.Builder
+	b.WriteString("<h2 id=\"summary\">Resumo</h2>\n<div class=\"panel\"><dl>\n")
+	htmlField(&b, "Arquivos", strconv.Itoa(len(info.Files)-len(info.Failed)))
+	htmlField(&b, "Binários ignorados", strconv.Itoa(info.SkippedBin))
+	htmlField(&b, "Sensíveis ignorados", strconv.Itoa(info.SkippedSecret))
+	if info.Tokenizer != "" {
+		htmlField(&b, "Tokens ("+info.Tokenizer+")", strconv.Itoa(info.Tokens))
+	}
+	if cfg.MaxTokens > 0 {
+		htmlField(&b, "Orçamento de tokens", fmt.Sprintf("%d de %d", info.Budget, cfg.MaxTokens))
+		if info.Cut != "" {
+			htmlField(&b, "Truncado para caber", DisplayPath(info.Cut, cfg))
+		}
+		if len(info.Omitted) > 0 {
+			om := make([]string, len(info.Omitted))
+			for i, p := range info.Omitted {
+				om[i] = DisplayPath(p, cfg)
+			}
+			htmlField(&b, fmt.Sprintf("Omitidos (%d)", len(om)), strings.Join(om, ", "))
+		}
+	}
+	for _, fe := range info.Failed {
+		htmlField(&b, "Falha", DisplayPath(fe.Path, cfg)+": "+fe.Reason())
+	}
+	if len(info.Redacted) > 0 {
+		var rb strings.Builder
+		if err := writeRedactions(&rb, cfg, info.Redacted); err != nil {
+			return err
+		}
+		b.WriteString("</dl><pre>")
+		b.WriteString(html.EscapeString(rb.String()))
+		b.WriteString("</pre><dl>\n")
+	}
+	b.WriteString("</dl></div>\n</main>\n")
+	writeHTMLTree(&b, cfg, info)
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHTMLTree escreve a árvore dos arquivos emitidos, na ordem de saída
+// (--order): cada diretório aparece onde surge o primeiro de seus arquivos.
+func writeHTMLTree(b *strings.Builder, cfg cli.Config, info FooterInfo) {
+	root := fileTree(cfg, DocInfo{Files: info.Files, Failed: info.Failed})
+	b.WriteString("<nav>\n<p><a href=\"#header\">Code Context</a> · <a href=\"#summary\">Resumo</a></p>\n<ul>\n")
+	for _, k := range root.kids {
+		writeTreeNode(b, k)
+	}
+	b.WriteString("</ul>\n</nav>\n")
+}
+
+func writeTreeNode(b *strings.Builder, n *treeNode) {
+	if n.leaf {
+		class := ""
+		if n.mark != "" {
+			class = ` class="` + n.mark + `"`
+		}
+		fmt.Fprintf(b, "<li><a href=\"#f%d\"%s>%s</a></li>\n", n.file.Index, class, html.EscapeString(n.name))
+		return
+	}
+	name, n := n.collapsed()
+	fmt.Fprintf(b, "<li><details open><summary>%s/</summary><ul>\n", html.EscapeString(name))
+	for _, k := range n.kids {
+		writeTreeNode(b, k)
+	}
+	b.WriteString("</ul></details></li>\n")
+}
+
+// renderOneHTML produz a <section> de um arquivo.
+func renderOneHTML(fm scan.FileMeta, cfg cli.Config, src *source) (block, error) {
+	rec, written, err := fileRec(fm, cfg, src)
+	if err != nil || rec == nil {
+		return block{}, err
+	}
+	var b strings.Builder
+	htmlRecord(&b, rec, syntaxFor(fm.Path, rec.Content))
+	out := b.String()
+	return block{buf: []byte(out), written: int64(written), tokens: blockTokens(cfg, out), content: rec.count, redactions: rec.Redactions}, nil
+}
+
+// htmlRecord escreve rec como <section id="f<index>">.
+func htmlRecord(b *strings.Builder, rec *jsonRec, syn *syntax) {
+	path := html.EscapeString(rec.Path)
+	fmt.Fprintf(b, "<section class=\"file\" id=\"f%d\">\n<h2><a href=\"#f%d\">%s</a></h2>\n", rec.Index, rec.Index, path)
+	if rec.Error != "" {
+		fmt.Fprintf(b, "<p class=\"meta error\">Erro: %s</p>\n</section>\n", html.EscapeString(rec.Error))
+		return
+	}
+	fmt.Fprintf(b, "<p class=\"meta\">Size: %d bytes · Hash: %s · Lines: %d", rec.Size, rec.Hash, rec.Lines)
+	if rec.Tokens != nil {
+		fmt.Fprintf(b, " · Tokens: %d", *rec.Tokens)
+	}
+	if rec.Score != nil {
+		fmt.Fprintf(b, " · Score: %s", strconv.FormatFloat(*rec.Score, 'f', -1, 64))
+	}
+	b.WriteString("</p>\n")
+	if rec.Content != "" {
+		b.WriteString("<pre class=\"code\"><code>")
+		b.WriteString(highlight(rec.Content, syn))
+		b.WriteString("</code></pre>\n")
+	}
+	if rec.Diff != "" {
+		b.WriteString("<p class=\"meta\">Diff:</p>\n<pre class=\"code\"><code>")
+		b.WriteString(highlightDiff(rec.Diff))
+		b.WriteString("</code></pre>\n")
+	}
+	if len(rec.Redactions) > 0 {
+		phs := make([]string, len(rec.Redactions))
+		for i, it := range rec.Redactions {
+			phs[i] = it.Placeholder
+		}
+		fmt.Fprintf(b, "<p class=\"meta\">Redações: %s</p>\n", html.EscapeString(strings.Join(phs, ", ")))
+	}
+	b.WriteString("</section>\n")
+}
+
+func htmlField(b *strings.Builder, name, value string) {
+	fmt.Fprintf(b, "<dt>%s</dt><dd>%s</dd>\n", html.EscapeString(name), html.EscapeString(value))
+}
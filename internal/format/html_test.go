@@ -0,0 +1,61 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestHTMLReport(t *testing.T) {
+	dir := t.TempDir()
+	fp1 := filepath.Join(dir, "cmd", "main.go")
+	fp2 := filepath.Join(dir, "README.md")
+	_ = os.MkdirAll(filepath.Dir(fp1), 0o755)
+	_ = os.WriteFile(fp1, []byte("package main\n\n/* a < b\n   && c */\nfunc main() { s := \"<x>\" }\n"), 0o644)
+	_ = os.WriteFile(fp2, []byte("# Título\n"), 0o644)
+
+	cfg := cli.Config{Format: "html", Paths: []string{dir}}
+	files := []scan.FileMeta{{Path: fp1, Index: 0}, {Path: fp2, Index: 1}}
+	var w bytes.Buffer
+	if err := format.WriteDocHeader(&w, cfg); err != nil {
+		t.Fatal(err)
+	}
+	m, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := format.WriteSummaryFooterInfo(&w, cfg, format.FooterInfo{SkippedSecret: 3, Files: m.Written}); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<section class="file" id="f0">`,
+		`<span class="l"><span class="k">package</span> main</span>`,
+		// comentário de bloco fechado e reaberto na quebra de linha
+		`<span class="c">/* a &lt; b</span></span>` + "\n" + `<span class="l"><span class="c">   &amp;&amp; c */</span></span>`,
+		`<span class="s">&#34;&lt;x&gt;&#34;</span>`,
+		`<dt>Sensíveis ignorados</dt><dd>3</dd>`,
+		`<a href="#f1">README.md</a>`,
+		"</html>\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("faltou %q na saída:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<script") || strings.Contains(out, "http") {
+		t.Errorf("o relatório não deve depender de assets externos")
+	}
+	nav := out[strings.Index(out, "<nav>"):]
+	if i, j := strings.Index(nav, `href="#f0"`), strings.Index(nav, `href="#f1"`); i < 0 || j < i {
+		t.Errorf("a árvore deveria seguir a ordem de saída")
+	}
+}
@@ -3,6 +3,8 @@ package format_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -38,3 +40,58 @@ func TestJobsKeepsDeterministicOrder(t *testing.T) {
 		t.Fatalf("ordem não determinística com jobs>1")
 	}
 }
+
+func TestMemoryCeilingKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	var metas []scan.FileMeta
+	for i := 0; i < 40; i++ {
+		fp := filepath.Join(dir, fmt.Sprintf("f%02d.txt", i))
+		data := strings.Repeat(fmt.Sprintf("linha %d\n", i), i*7+1)
+		_ = os.WriteFile(fp, []byte(data), 0o644)
+		metas = append(metas, scan.FileMeta{Path: fp, Size: int64(len(data)), Index: i})
+	}
+	render := func(cfg cli.Config) string {
+		var w bytes.Buffer
+		if _, err := format.ProcessFiles(context.TODO(), &w, metas, cfg, nil); err != nil {
+			t.Fatal(err)
+		}
+		return w.String()
+	}
+	for _, f := range []string{"plain", "json"} {
+		want := render(cli.Config{Format: f, Jobs: 1})
+		if got := render(cli.Config{Format: f, Jobs: 8, MaxMemory: 1}); got != want {
+			t.Fatalf("%s: saída com --max-memory 1 difere da sequencial", f)
+		}
+	}
+}
+
+// cancelWriter cancela o contexto na primeira escrita.
+type cancelWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestStreamsBeforeAllRendered(t *testing.T) {
+	dir := t.TempDir()
+	var metas []scan.FileMeta
+	for i := 0; i < 200; i++ {
+		fp := filepath.Join(dir, fmt.Sprintf("f%03d.txt", i))
+		_ = os.WriteFile(fp, []byte("x\n"), 0o644)
+		metas = append(metas, scan.FileMeta{Path: fp, Size: 2, Index: i})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &cancelWriter{cancel: cancel}
+	// a primeira escrita acontece antes do fim e cancela o resto
+	_, err := format.ProcessFiles(ctx, w, metas, cli.Config{Format: "plain", Jobs: 2}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("esperava context.Canceled, got %v", err)
+	}
+	if n := strings.Count(w.String(), "FILE: "); n == 0 || n == len(metas) {
+		t.Fatalf("esperava saída parcial, got %d blocos", n)
+	}
+}
@@ -0,0 +1,129 @@
+package format
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Linguagem do bloco de código (markdown, fenced, realce do html e .Lang dos
+// templates): pelo nome do arquivo, pela extensão e, sem nenhum dos dois,
+// pelo shebang da primeira linha. As tags são as que os renderizadores de
+// markdown (linguist, highlight.js) reconhecem.
+
+var langByName = map[string]string{
+	"makefile":       "makefile",
+	"gnumakefile":    "makefile",
+	"dockerfile":     "dockerfile",
+	"containerfile":  "dockerfile",
+	"cmakelists.txt": "cmake",
+	"jenkinsfile":    "groovy",
+	"gemfile":        "ruby",
+	"rakefile":       "ruby",
+	"vagrantfile":    "ruby",
+	"podfile":        "ruby",
+	"build":          "starlark",
+	"build.bazel":    "starlark",
+	"workspace":      "starlark",
+	"go.mod":         "go-mod",
+	"go.sum":         "text",
+	".bashrc":        "bash",
+	".bash_profile":  "bash",
+	".profile":       "sh",
+	".zshrc":         "zsh",
+	".gitignore":     "gitignore",
+	".dockerignore":  "gitignore",
+	".gitattributes": "gitattributes",
+	".editorconfig":  "ini",
+	".env":           "dotenv",
+}
+
+var langByExt = map[string]string{
+	".go": "go",
+	".py": "python", ".pyi": "python", ".pyw": "python",
+	".js": "javascript", ".mjs": "javascript", ".cjs": "javascript", ".jsx": "jsx",
+	".ts": "typescript", ".mts": "typescript", ".cts": "typescript", ".tsx": "tsx",
+	".rb": "ruby", ".rake": "ruby", ".gemspec": "ruby",
+	".rs":   "rust",
+	".java": "java", ".kt": "kotlin", ".kts": "kotlin", ".scala": "scala", ".groovy": "groovy", ".gradle": "groovy",
+	".swift": "swift", ".dart": "dart", ".cs": "csharp", ".fs": "fsharp",
+	".c": "c", ".h": "c",
+	".cc": "cpp", ".cpp": "cpp", ".cxx": "cpp", ".hh": "cpp", ".hpp": "cpp", ".hxx": "cpp",
+	".m": "objectivec", ".mm": "objectivec",
+	".php": "php", ".pl": "perl", ".pm": "perl", ".lua": "lua", ".r": "r",
+	".ex": "elixir", ".exs": "elixir", ".erl": "erlang", ".hs": "haskell", ".clj": "clojure",
+	".ml": "ocaml", ".zig": "zig", ".nim": "nim", ".jl": "julia",
+	".sh": "bash", ".bash": "bash", ".zsh": "zsh", ".fish": "fish", ".ps1": "powershell",
+	".bat": "batch", ".cmd": "batch",
+	".yml": "yaml", ".yaml": "yaml", ".json": "json", ".jsonc": "jsonc", ".toml": "toml",
+	".ini": "ini", ".cfg": "ini", ".conf": "ini", ".properties": "properties",
+	".xml": "xml", ".svg": "xml", ".html": "html", ".htm": "html",
+	".css": "css", ".scss": "scss", ".sass": "sass", ".less": "less",
+	".vue": "vue", ".svelte": "svelte",
+	".md": "markdown", ".markdown": "markdown", ".rst": "rst", ".tex": "latex",
+	".sql": "sql", ".graphql": "graphql", ".gql": "graphql", ".proto": "protobuf",
+	".tf": "hcl", ".hcl": "hcl", ".nix": "nix", ".mk": "makefile", ".cmake": "cmake",
+	".dockerfile": "dockerfile", ".diff": "diff", ".patch": "diff", ".txt": "text",
+	".tmpl": "go-template", ".gotmpl": "go-template",
+}
+
+// langByInterpreter traduz o interpretador do shebang.
+var langByInterpreter = map[string]string{
+	"sh": "sh", "bash": "bash", "zsh": "zsh", "ksh": "sh", "dash": "sh", "fish": "fish",
+	"python": "python", "node": "javascript", "deno": "typescript", "ruby": "ruby",
+	"perl": "perl", "php": "php", "lua": "lua", "Rscript": "r", "pwsh": "powershell",
+	"make": "makefile", "awk": "awk", "tclsh": "tcl",
+}
+
+// langFor devolve a tag de linguagem de path; body (pode ser vazio) só é
+// consultado pelo shebang. Extensões desconhecidas viram a própria tag.
+func langFor(path, body string) string {
+	base := strings.ToLower(filepath.Base(path))
+	if l, ok := langByName[base]; ok {
+		return l
+	}
+	if strings.HasPrefix(base, "dockerfile.") || strings.HasSuffix(base, ".dockerfile") {
+		return "dockerfile"
+	}
+	if strings.HasPrefix(base, ".env.") {
+		return "dotenv"
+	}
+	ext := filepath.Ext(base)
+	if l, ok := langByExt[ext]; ok {
+		return l
+	}
+	if l := shebangLang(body); l != "" {
+		return l
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+// shebangLang reconhece "#!/usr/bin/env python3", "#!/bin/bash -e" e afins.
+func shebangLang(body string) string {
+	if !strings.HasPrefix(body, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(body[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		// env -S e outras opções vêm antes do interpretador
+		interp = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interp = filepath.Base(f)
+				break
+			}
+		}
+	}
+	if l, ok := langByInterpreter[interp]; ok {
+		return l
+	}
+	// versões no nome: python3, python3.12, ruby2.7, perl5
+	if l, ok := langByInterpreter[strings.TrimRight(interp, "0123456789.")]; ok {
+		return l
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func renderFenced(t *testing.T, name, body string, cfg cli.Config) string {
+	t.Helper()
+	dir := t.TempDir()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var w bytes.Buffer
+	fm := scan.FileMeta{Path: p, Size: int64(len(body))}
+	if _, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{fm}, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	return w.String()
+}
+
+func TestFencedLanguageDetection(t *testing.T) {
+	cases := []struct{ name, body, lang string }{
+		{"Makefile", "all:\n\tgo build\n", "makefile"},
+		{"Dockerfile", "FROM scratch\n", "dockerfile"},
+		{"Dockerfile.dev", "FROM scratch\n", "dockerfile"},
+		{"ci.yml", "on: push\n", "yaml"},
+		{"util.h", "int f(void);\n", "c"},
+		{"main.go", "package main\n", "go"},
+		{"deploy", "#!/usr/bin/env -S python3 -u\nprint(1)\n", "python"},
+		{"run", "#!/bin/bash -e\necho ok\n", "bash"},
+		{"data.xyz", "x\n", "xyz"},
+	}
+	for _, c := range cases {
+		out := renderFenced(t, c.name, c.body, cli.Config{Format: "fenced"})
+		if !strings.Contains(out, "\n```"+c.lang+"\n# File: ") {
+			t.Errorf("%s: esperava ```%s; got:\n%s", c.name, c.lang, out)
+		}
+		out = renderFenced(t, c.name, c.body, cli.Config{Format: "markdown"})
+		if !strings.Contains(out, "\n```"+c.lang+"\n") {
+			t.Errorf("%s (markdown): esperava ```%s; got:\n%s", c.name, c.lang, out)
+		}
+	}
+}
+
+func TestFenceLongerThanBody(t *testing.T) {
+	body := "# Exemplo\n\n```go\nfmt.Println(1)\n```\n\n````\naninhado\n````\n"
+	for _, f := range []string{"markdown", "fenced"} {
+		out := renderFenced(t, "README.md", body, cli.Config{Format: f})
+		if !strings.Contains(out, "`````markdown\n") {
+			t.Fatalf("%s: cerca deveria ter 5 crases; got:\n%s", f, out)
+		}
+		if !strings.HasSuffix(strings.TrimRight(out, "\n"), "\n`````") {
+			t.Fatalf("%s: bloco deveria fechar com 5 crases; got:\n%s", f, out)
+		}
+	}
+
+	out := renderFenced(t, "a.txt", "sem crases\n", cli.Config{Format: "fenced"})
+	if !strings.Contains(out, "\n```text\n") || strings.Contains(out, "````") {
+		t.Fatalf("sem crases no corpo a cerca deveria ter 3; got:\n%s", out)
+	}
+}
@@ -0,0 +1,136 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/filters"
+)
+
+// Redaction é um segredo substituído por --redact.
+type Redaction struct {
+	Placeholder string `json:"placeholder"`
+	Rule        string `json:"rule"`
+	Line        int    `json:"line"`           // linha no arquivo original (ou no diff)
+	Diff        bool   `json:"diff,omitempty"` // encontrado no diff, não no conteúdo
+	Fingerprint string `json:"fingerprint"`    // para o allowlist de --secret-rules
+}
+
+// FileRedactions agrupa as redações de um arquivo para o rodapé.
+type FileRedactions struct {
+	Path  string
+	Items []Redaction
+}
+
+// redactor troca os segredos de um arquivo por marcadores estáveis: o mesmo
+// valor recebe sempre o mesmo número, na ordem em que aparece, no conteúdo e
+// no diff do arquivo.
+type redactor struct {
+	path  string
+	cfg   cli.Config
+	seen  map[string]string // regra + valor -> marcador
+	count map[string]int    // regra -> último número usado
+	items []Redaction
+}
+
+// newRedactor devolve nil sem --redact; os métodos aceitam receptor nil. As
+// regras e o allowlist são os de cfg (--secret-rules) para o arquivo path.
+func newRedactor(path string, cfg cli.Config) *redactor {
+	if !cfg.Redact {
+		return nil
+	}
+	return &redactor{path: path, cfg: cfg, seen: map[string]string{}, count: map[string]int{}}
+}
+
+// apply devolve data com os segredos substituídos. As quebras de linha de
+// um segredo de várias linhas (bloco PEM) são mantidas depois do marcador,
+// para que a numeração das linhas continue a do original.
+func (rd *redactor) apply(data []byte, inDiff bool) []byte {
+	if rd == nil {
+		return data
+	}
+	fs := filters.Findings(rd.path, data, rd.cfg)
+	if len(fs) == 0 {
+		return data
+	}
+	var out bytes.Buffer
+	out.Grow(len(data))
+	last := 0
+	for _, f := range fs {
+		secret := data[f.Start:f.End]
+		ph := rd.placeholder(f.Rule, string(secret))
+		rd.items = append(rd.items, Redaction{Placeholder: ph, Rule: f.Rule, Line: f.Line, Diff: inDiff, Fingerprint: f.Fingerprint})
+		out.Write(data[last:f.Start])
+		out.WriteString(ph)
+		out.Write(bytes.Repeat([]byte{'\n'}, bytes.Count(secret, []byte{'\n'})))
+		last = f.End
+	}
+	out.Write(data[last:])
+	return out.Bytes()
+}
+
+func (rd *redactor) applyString(s string, inDiff bool) string {
+	if rd == nil || s == "" {
+		return s
+	}
+	return string(rd.apply([]byte(s), inDiff))
+}
+
+func (rd *redactor) placeholder(rule, value string) string {
+	key := rule + "\x00" + value
+	if ph, ok := rd.seen[key]; ok {
+		return ph
+	}
+	rd.count[rule]++
+	ph := fmt.Sprintf("[REDACTED:%s#%d]", rule, rd.count[rule])
+	rd.seen[key] = ph
+	return ph
+}
+
+// writeRedactions lista no rodapé os marcadores de cada arquivo:
+//
+//	Redações (3 em 2 arquivos):
+//	  - cmd/app/config.go: [REDACTED:aws-access-key-id#1] (linha 12)
+func writeRedactions(w io.Writer, cfg cli.Config, files []FileRedactions) error {
+	n := 0
+	for _, f := range files {
+		n += len(f.Items)
+	}
+	if _, err := fmt.Fprintf(w, "Redações (%d em %d arquivos):\n", n, len(files)); err != nil {
+		return err
+	}
+	for _, f := range files {
+		parts := make([]string, len(f.Items))
+		for i, it := range f.Items {
+			where := "linha"
+			if it.Diff {
+				where = "linha do diff"
+			}
+			parts[i] = fmt.Sprintf("%s (%s %d)", it.Placeholder, where, it.Line)
+		}
+		if _, err := fmt.Fprintf(w, "  - %s: %s\n", DisplayPath(f.Path, cfg), strings.Join(parts, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentRedactor devolve o redator do conteúdo: com --diff-only o conteúdo
+// não é emitido e só o diff é redigido.
+func contentRedactor(rd *redactor, cfg cli.Config) *redactor {
+	if cfg.DiffOnly {
+		return nil
+	}
+	return rd
+}
+
+// redactions devolve as redações feitas até aqui.
+func (rd *redactor) redactions() []Redaction {
+	if rd == nil {
+		return nil
+	}
+	return rd.items
+}
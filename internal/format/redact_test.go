@@ -0,0 +1,71 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestRedactKeepsHashAndLines(t *testing.T) {
+	k1, k2 := "AKIA"+"IOSFODNN7EXAMPLE", "AKIA"+"ZZZZZZZZZZZZZZZZ"
+	data := "a = \"" + k1 + "\"\n" +
+		"-----BEGIN EC " + "PRIVATE KEY-----\nMHcCAQEE\n-----END EC " + "PRIVATE KEY-----\n" +
+		"b = \"" + k2 + "\"\nc = \"" + k1 + "\"\n"
+	p := filepath.Join(t.TempDir(), "cfg.txt")
+	_ = os.WriteFile(p, []byte(data), 0o644)
+	fm := scan.FileMeta{Path: p, Size: int64(len(data))}
+	sum := sha256.Sum256([]byte(data))
+
+	var w bytes.Buffer
+	cfg := cli.Config{Format: "plain", Redact: true}
+	m, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{fm}, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	if strings.Contains(out, "AKIA") || strings.Contains(out, "MHcCAQEE") {
+		t.Fatalf("segredo vazou:\n%s", out)
+	}
+	if !strings.Contains(out, "HASH: "+hex.EncodeToString(sum[:])[:8]+" | LINES: 6\n") {
+		t.Fatalf("hash/linhas deveriam ser do original:\n%s", out)
+	}
+	body := "a = \"[REDACTED:aws-access-key-id#1]\"\n[REDACTED:private-key#1]\n\n\n" +
+		"b = \"[REDACTED:aws-access-key-id#2]\"\nc = \"[REDACTED:aws-access-key-id#1]\"\n"
+	if !strings.Contains(out, body) {
+		t.Fatalf("corpo redigido inesperado:\n%s", out)
+	}
+	if len(m.Redacted) != 1 || len(m.Redacted[0].Items) != 4 || m.Redacted[0].Items[3].Line != 6 {
+		t.Fatalf("redações inesperadas: %+v", m.Redacted)
+	}
+
+	var foot bytes.Buffer
+	_ = format.WriteSummaryFooterInfo(&foot, cfg, format.FooterInfo{Redacted: m.Redacted})
+	if !strings.Contains(foot.String(), "Redações (4 em 1 arquivos):") || !strings.Contains(foot.String(), "[REDACTED:private-key#1] (linha 2)") {
+		t.Fatalf("rodapé sem as redações:\n%s", foot.String())
+	}
+
+	w.Reset()
+	if _, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{fm}, cli.Config{Format: "json", Redact: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var rec struct {
+		Content    string
+		Redactions []format.Redaction
+	}
+	if err := json.Unmarshal(w.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Content != body || len(rec.Redactions) != 4 || rec.Redactions[1].Rule != "private-key" {
+		t.Fatalf("registro JSON inesperado: %+v", rec)
+	}
+}
@@ -0,0 +1,222 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/filters"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// snapshot é o resultado de uma única leitura de um arquivo: tamanho, hash,
+// linhas e corpo vêm dos mesmos bytes, mesmo que o arquivo mude no meio da
+// execução.
+type snapshot struct {
+	size    int64
+	hash    string
+	lines   int
+	body    string // conteúdo com --max-lines/--max-cols aplicados
+	written int    // bytes de conteúdo em body
+	cutCols bool   // alguma linha de body foi cortada por --max-cols
+	full    string // conteúdo inteiro, apenas quando pedido (tokens em --index-only)
+}
+
+// secretSkip descarta um arquivo cujo conteúdo casou uma regra de segredo
+// ao ser lido; ProcessFiles o registra em Metrics.SecretSkips em vez de
+// tratá-lo como falha.
+type secretSkip struct{ d filters.Decision }
+
+func (e *secretSkip) Error() string { return "conteúdo sensível (" + e.d.Rule + ")" }
+
+// source guarda o que a renderização de um arquivo lê de fora: os bytes e os
+// hunks do diff. Com --max-tokens o bloco pode ser renderizado de novo com
+// outro --max-lines (truncateToFit), sem reler o arquivo nem rodar git; nil
+// lê do disco (ou do blob) e calcula o diff a cada renderização.
+type source struct {
+	data []byte
+	diff string
+}
+
+// loadSource lê fm e os hunks do diff uma vez. Em --diff-only, um arquivo
+// sem alterações não é lido: não há nada a mostrar.
+func loadSource(fm scan.FileMeta, cfg cli.Config) (*source, error) {
+	diff, err := diffFor(fm, cfg)
+	if err != nil {
+		return nil, err
+	}
+	src := &source{diff: diff}
+	if cfg.DiffOnly && diff == "" {
+		return src, nil
+	}
+	if fm.Rev != nil {
+		src.data, err = fm.ReadBlob()
+		return src, err
+	}
+	f, err := util.OpenRead(fm.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src.data, err = io.ReadAll(f)
+	return src, err
+}
+
+// diffFor devolve os hunks já lidos, ou os calcula quando s é nil.
+func (s *source) diffFor(fm scan.FileMeta, cfg cli.Config) (string, error) {
+	if s != nil {
+		return s.diff, nil
+	}
+	return diffFor(fm, cfg)
+}
+
+// size devolve os bytes retidos por s (janela de reordenação).
+func (s *source) size() int64 {
+	if s == nil {
+		return 0
+	}
+	return int64(len(s.data) + len(s.diff))
+}
+
+// readSnapshot lê fm uma vez, de from, do disco ou do blob (--rev). withBody
+// monta o corpo; keepFull guarda o conteúdo inteiro. Com rd (--redact) e com
+// --anonymize, corpo e conteúdo inteiro saem redigidos e anonimizados, mas
+// tamanho, hash e linhas continuam os do original. Falhas de leitura
+// (arquivo removido, sem permissão, linha acima de maxLineBytes) são
+// devolvidas para virarem um FileError.
+func readSnapshot(fm scan.FileMeta, cfg cli.Config, from *source, rd *redactor, withBody, keepFull bool) (snapshot, error) {
+	var r io.Reader
+	if from != nil {
+		r = bytes.NewReader(from.data)
+	} else if fm.Rev != nil {
+		data, err := fm.ReadBlob()
+		if err != nil {
+			return snapshot{}, err
+		}
+		r = bytes.NewReader(data)
+	} else {
+		f, err := util.OpenRead(fm.Path)
+		if err != nil {
+			return snapshot{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	h := sha256.New()
+	var full bytes.Buffer
+	src := io.TeeReader(r, h)
+	if fm.Rev == nil && cfg.SecretsStrict && !cfg.Redact {
+		// secret-content sobre o início do que já está sendo lido: a listagem
+		// não abre o arquivo (filters.DecideListed)
+		prefix, err := io.ReadAll(io.LimitReader(src, filters.MaxScanBytes))
+		if err != nil {
+			return snapshot{}, err
+		}
+		if d := filters.DecideContent(fm.Path, prefix, cfg); !d.Include {
+			return snapshot{}, &secretSkip{d: d}
+		}
+		src = io.MultiReader(bytes.NewReader(prefix), src)
+	}
+	size := int64(-1)
+	if an := anonymizer(cfg); rd != nil || an != nil {
+		// a redação precisa do arquivo inteiro (segredos de várias linhas)
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return snapshot{}, err
+		}
+		size = int64(len(data))
+		src = bytes.NewReader(an.Bytes(rd.apply(data, false)))
+	}
+	if keepFull {
+		src = io.TeeReader(src, &full)
+	}
+	br := bufio.NewReaderSize(src, 64*1024)
+
+	var body strings.Builder
+	lw := lineWriter{b: &body, maxLines: cfg.MaxLines, maxCols: cfg.MaxCols, done: !withBody}
+	s := snapshot{}
+	for {
+		line, err := readLine(br, maxLineBytes)
+		s.size += int64(len(line))
+		if line != "" {
+			s.lines++
+			lw.add(line)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return snapshot{}, err
+		}
+	}
+	if size >= 0 {
+		s.size = size
+	}
+	sum := h.Sum(nil)
+	s.hash = hex.EncodeToString(sum)[:8]
+	s.body, s.written, s.cutCols = body.String(), lw.written, lw.cutCols
+	if keepFull {
+		s.full = full.String()
+	}
+	return s, nil
+}
+
+// readLine lê até o próximo "\n" (inclusive), recusando linhas com mais de
+// max bytes.
+func readLine(br *bufio.Reader, max int) (string, error) {
+	var long []byte
+	for {
+		frag, err := br.ReadSlice('\n')
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			if long == nil {
+				return string(frag), err
+			}
+			return string(append(long, frag...)), err
+		}
+		long = append(long, frag...)
+		if len(long) > max {
+			return "", fmt.Errorf("%w: mais de %d bytes", ErrLineTooLong, max)
+		}
+	}
+}
+
+// lineWriter copia linhas para b aplicando os limites de linhas e colunas;
+// depois do limite (ou com done), as linhas são apenas descartadas.
+type lineWriter struct {
+	b        *strings.Builder
+	maxLines int
+	maxCols  int
+	lines    int
+	written  int
+	done     bool
+	cutCols  bool // alguma linha passou de maxCols
+}
+
+// add recebe uma linha com ou sem o "\n" final.
+func (lw *lineWriter) add(line string) {
+	if lw.done {
+		return
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	if lw.maxCols > 0 && len([]rune(line)) > lw.maxCols {
+		line = truncateCols(line, lw.maxCols)
+		lw.cutCols = true
+	}
+	lw.b.WriteString(line)
+	lw.b.WriteByte('\n')
+	lw.written += len(line) + 1
+	lw.lines++
+	if lw.maxLines > 0 && lw.lines >= lw.maxLines {
+		fmt.Fprintf(lw.b, "\n[... truncado em %d linhas ...]\n", lw.maxLines)
+		lw.done = true
+	}
+}
@@ -0,0 +1,86 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestHeaderAndBodyFromSameRead(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.txt")
+	data := "um\r\ndois\ntrês" // sem \n final
+	_ = os.WriteFile(p, []byte(data), 0o644)
+	sum := sha256.Sum256([]byte(data))
+
+	// Size desatualizado de propósito: o cabeçalho deve refletir o que foi lido
+	fm := scan.FileMeta{Path: p, Size: 1, Index: 0}
+	var w bytes.Buffer
+	if _, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{fm}, cli.Config{Format: "plain", MaxLines: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("SIZE: %d bytes | HASH: %s | LINES: 3\n", len(data), hex.EncodeToString(sum[:])[:8])
+	if !strings.Contains(w.String(), want) {
+		t.Fatalf("esperava %q em:\n%s", want, w.String())
+	}
+	if !strings.Contains(w.String(), "\num\n\n[... truncado em 1 linhas ...]\n") {
+		t.Fatalf("corpo inesperado:\n%s", w.String())
+	}
+}
+
+// A etapa secret-content roda sobre os bytes da leitura da emissão: o
+// arquivo sai do documento e volta como SecretSkip.
+func TestSecretContentCheckedOnRead(t *testing.T) {
+	dir := t.TempDir()
+	leak := filepath.Join(dir, "config.go")
+	_ = os.WriteFile(leak, []byte("package cfg\nconst k = \"AKIA"+"IOSFODNN7EXAMPLE\"\n"), 0o644)
+	ok := filepath.Join(dir, "main.go")
+	_ = os.WriteFile(ok, []byte("package main\n"), 0o644)
+
+	files := []scan.FileMeta{{Path: leak, Index: 0}, {Path: ok, Index: 1}}
+	for _, f := range []string{"plain", "json"} {
+		var w bytes.Buffer
+		m, err := format.ProcessFiles(context.TODO(), &w, files, cli.Config{Format: f, SecretsStrict: true}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(w.String(), "AKIA") || m.Files != 1 || len(m.Written) != 1 {
+			t.Fatalf("%s: config.go deveria ficar de fora (files=%d):\n%s", f, m.Files, w.String())
+		}
+		if len(m.SecretSkips) != 1 || m.SecretSkips[0].Path != leak {
+			t.Fatalf("%s: SecretSkips inesperado: %+v", f, m.SecretSkips)
+		}
+		d := m.SecretSkips[0].Decision
+		if d.Reason != "secret-content" || d.Rule != "aws-access-key-id" || len(d.Findings) != 1 || d.Findings[0].Line != 2 {
+			t.Fatalf("%s: decisão inesperada: %+v", f, d)
+		}
+	}
+}
+
+func BenchmarkProcessFiles(b *testing.B) {
+	dir := b.TempDir()
+	var files []scan.FileMeta
+	line := "\tif err := process(ctx, items[i]); err != nil { return fmt.Errorf(\"item %d: %w\", i, err) }\n"
+	for i := 0; i < 200; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("f%03d.txt", i))
+		_ = os.WriteFile(p, []byte(strings.Repeat(line, 500)), 0o644)
+		files = append(files, scan.FileMeta{Path: p, Index: i})
+	}
+	cfg := cli.Config{Format: "plain"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := format.ProcessFiles(context.TODO(), &bytes.Buffer{}, files, cfg, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
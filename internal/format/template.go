@@ -0,0 +1,219 @@
+package format
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/tmpl"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// Formato template (-F template --template arquivo): os blocos "header",
+// "file" e "footer" do template (veja o pacote tmpl) recebem os dados abaixo.
+// Caminhos já vêm como no restante do documento (DisplayPath).
+
+// TemplateConfig é a parte da configuração visível aos templates: as opções
+// que descrevem o documento. Caminhos de arquivos auxiliares (dicionário de
+// pseudônimos, regras de segredo, saída) e os padrões de -x/-i ficam de fora,
+// para que o template não exponha o que --anonymize-paths esconde.
+type TemplateConfig struct {
+	ExtCSV      string
+	Depth       int
+	Order       string
+	MaxBytes    int64
+	MaxLines    int
+	MaxCols     int
+	MaxTokens   int
+	Tokenizer   string // codificação da contagem exata ("" quando os tokens são estimados)
+	IndexOnly   bool
+	WithDiff    bool
+	DiffOnly    bool
+	Redact      bool
+	PolicyHash  string
+	PolicyFiles []string
+}
+
+func templateConfig(cfg cli.Config) TemplateConfig {
+	return TemplateConfig{
+		ExtCSV:      cfg.ExtCSV,
+		Depth:       cfg.Depth,
+		Order:       cfg.Order,
+		MaxBytes:    cfg.MaxBytes,
+		MaxLines:    cfg.MaxLines,
+		MaxCols:     cfg.MaxCols,
+		MaxTokens:   cfg.MaxTokens,
+		Tokenizer:   exactTokenizer(cfg),
+		IndexOnly:   cfg.IndexOnly,
+		WithDiff:    cfg.WithDiff,
+		DiffOnly:    cfg.DiffOnly,
+		Redact:      cfg.Redact,
+		PolicyHash:  cfg.PolicyHash,
+		PolicyFiles: displayPaths(cfg.PolicyFiles, cfg),
+	}
+}
+
+// TemplateDoc são os dados do bloco "header".
+type TemplateDoc struct {
+	Config    TemplateConfig
+	Generated time.Time
+	Paths     []string // raízes (-p)
+	Files     []string // arquivos emitidos, na ordem de saída
+	Revision  string   // --rev
+	Changes   string   // modo de alterações ativo
+	Deleted   []string // removidos no modo de alterações
+}
+
+// TemplateFile são os dados do bloco "file", um por arquivo.
+type TemplateFile struct {
+	Config         TemplateConfig
+	Path           string
+	Size           int64
+	Hash           string
+	Lines          int
+	Tokens         int // tokens do conteúdo emitido (do arquivo inteiro em --index-only); 0 sem Config.Tokenizer
+	MTime          time.Time
+	Ext            string
+	Lang           string // linguagem do bloco de código
+	Index          int
+	Score          float64 // --order relevance
+	Content        string  // vazio em --index-only e --diff-only
+	Diff           string  // --with-diff/--diff-only
+	Error          string  // --keep-going: falha ao ler o arquivo
+	TruncatedLines bool    // conteúdo cortado por --max-lines
+	TruncatedCols  bool    // alguma linha cortada por --max-cols
+	Redactions     []Redaction
+}
+
+// TemplateFooter são os dados do bloco "footer".
+type TemplateFooter struct {
+	Config        TemplateConfig
+	Generated     time.Time
+	Paths         []string
+	Files         int // arquivos emitidos (sem as falhas)
+	SkippedBinary int
+	SkippedSecret int
+	Tokens        int    // soma dos Tokens dos arquivos
+	Tokenizer     string // tokens.Estimated sem --tokenizer/--max-tokens
+	Budget        int    // --max-tokens: tokens dos blocos admitidos
+	Failed        []TemplateFailure
+	Omitted       []string // --max-tokens: deixados de fora
+	Cut           string   // --max-tokens: truncado para caber
+	Redacted      []FileRedactions
+}
+
+// TemplateFailure é um arquivo que falhou com --keep-going.
+type TemplateFailure struct {
+	Path   string
+	Reason string
+}
+
+// execTemplate executa o bloco name do template de cfg; blocos ausentes não
+// escrevem nada.
+func execTemplate(w io.Writer, cfg cli.Config, name string, data any) error {
+	t, err := tmpl.Load(cfg.Template)
+	if err != nil {
+		return err
+	}
+	if t.Lookup(name) == nil {
+		return nil
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
+
+func displayPaths(ps []string, cfg cli.Config) []string {
+	out := make([]string, len(ps))
+	for i, p := range ps {
+		out[i] = DisplayPath(p, cfg)
+	}
+	return out
+}
+
+func writeTemplateHeader(w io.Writer, cfg cli.Config, info DocInfo) error {
+	files := make([]string, len(info.Files))
+	for i, fm := range info.Files {
+		files[i] = DisplayPath(fm.Path, cfg)
+	}
+	return execTemplate(w, cfg, "header", TemplateDoc{
+		Config:    templateConfig(cfg),
+		Generated: util.Now(),
+		Paths:     displayPaths(cfg.Paths, cfg),
+		Files:     files,
+		Revision:  info.Revision,
+		Changes:   info.Changes,
+		Deleted:   displayPaths(info.Deleted, cfg),
+	})
+}
+
+func writeTemplateFooter(w io.Writer, cfg cli.Config, info FooterInfo) error {
+	failed := make([]TemplateFailure, len(info.Failed))
+	for i, fe := range info.Failed {
+		failed[i] = TemplateFailure{Path: DisplayPath(fe.Path, cfg), Reason: fe.Reason()}
+	}
+	redacted := make([]FileRedactions, len(info.Redacted))
+	for i, r := range info.Redacted {
+		redacted[i] = FileRedactions{Path: DisplayPath(r.Path, cfg), Items: r.Items}
+	}
+	cut := ""
+	if info.Cut != "" {
+		cut = DisplayPath(info.Cut, cfg)
+	}
+	return execTemplate(w, cfg, "footer", TemplateFooter{
+		Config:        templateConfig(cfg),
+		Generated:     util.Now(),
+		Paths:         displayPaths(cfg.Paths, cfg),
+		Files:         len(info.Files) - len(info.Failed),
+		SkippedBinary: info.SkippedBin,
+		SkippedSecret: info.SkippedSecret,
+		Tokens:        info.Tokens,
+		Tokenizer:     info.Tokenizer,
+		Budget:        info.Budget,
+		Failed:        failed,
+		Omitted:       displayPaths(info.Omitted, cfg),
+		Cut:           cut,
+		Redacted:      redacted,
+	})
+}
+
+// renderOneTemplate executa o bloco "file" para fm.
+func renderOneTemplate(fm scan.FileMeta, cfg cli.Config, src *source) (block, error) {
+	rec, written, err := fileRec(fm, cfg, src)
+	if err != nil || rec == nil {
+		return block{}, err
+	}
+	var b strings.Builder
+	if err := execTemplate(&b, cfg, "file", templateFile(fm, cfg, rec)); err != nil {
+		return block{}, err
+	}
+	out := b.String()
+	return block{buf: []byte(out), written: int64(written), tokens: blockTokens(cfg, out), content: rec.count, redactions: rec.Redactions}, nil
+}
+
+func templateFile(fm scan.FileMeta, cfg cli.Config, rec *jsonRec) TemplateFile {
+	tf := TemplateFile{
+		Config:         templateConfig(cfg),
+		Path:           rec.Path,
+		Size:           rec.Size,
+		Hash:           rec.Hash,
+		Lines:          rec.Lines,
+		MTime:          time.Unix(rec.MTime, 0),
+		Ext:            rec.Ext,
+		Lang:           langFor(fm.Path, rec.Content),
+		Index:          rec.Index,
+		Content:        rec.Content,
+		Diff:           rec.Diff,
+		Error:          rec.Error,
+		TruncatedLines: rec.cutLines,
+		TruncatedCols:  rec.cutCols,
+		Redactions:     rec.Redactions,
+	}
+	if rec.Tokens != nil {
+		tf.Tokens = *rec.Tokens
+	}
+	if rec.Score != nil {
+		tf.Score = *rec.Score
+	}
+	return tf
+}
@@ -0,0 +1,81 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func renderDoc(t *testing.T, cfg cli.Config, files []scan.FileMeta) string {
+	t.Helper()
+	var w bytes.Buffer
+	if err := format.WriteDocHeaderInfo(&w, cfg, format.DocInfo{Files: files}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sem a linha de tokens: o total dos blocos depende de como são contados
+	foot := format.FooterInfo{SkippedBin: 1, Files: m.Written}
+	if err := format.WriteSummaryFooterInfo(&w, cfg, foot); err != nil {
+		t.Fatal(err)
+	}
+	// o horário muda entre as execuções
+	var out []string
+	for _, l := range strings.Split(w.String(), "\n") {
+		if !strings.HasPrefix(l, "# Generated:") {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// Os templates de exemplo reproduzem os formatos embutidos.
+func TestExampleTemplatesMatchBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	fp1 := filepath.Join(dir, "main.go")
+	fp2 := filepath.Join(dir, "notes.txt")
+	_ = os.WriteFile(fp1, []byte("package main\n\nfunc main() {}\n"), 0o644)
+	_ = os.WriteFile(fp2, []byte("sem quebra no fim"), 0o644)
+	files := []scan.FileMeta{{Path: fp1, Index: 0}, {Path: fp2, Index: 1}}
+
+	for _, name := range []string{"plain", "markdown", "fenced"} {
+		t.Run(name, func(t *testing.T) {
+			cfg := cli.Config{Format: name, Paths: []string{dir}, ExtCSV: "go,txt", Tokenizer: "cl100k_base"}
+			want := renderDoc(t, cfg, files)
+			cfg.Format = "template"
+			cfg.Template = filepath.Join("..", "..", "examples", "templates", name+".tmpl")
+			got := renderDoc(t, cfg, files)
+			if got != want {
+				t.Errorf("template difere do formato embutido:\n--- got\n%s\n--- want\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestTemplateFileData(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "a.go")
+	_ = os.WriteFile(fp, []byte("um\ndois\ntrês\n"), 0o644)
+	tpl := filepath.Join(dir, "t.tmpl")
+	body := `{{define "file"}}{{.Path | xml}}|{{.Lines}}|{{.TruncatedLines}}|{{indent 2 .Content}}{{end}}`
+	_ = os.WriteFile(tpl, []byte(body), 0o644)
+
+	cfg := cli.Config{Format: "template", Template: tpl, MaxLines: 1, Paths: []string{dir}}
+	var w bytes.Buffer
+	if _, err := format.ProcessFiles(context.TODO(), &w, []scan.FileMeta{{Path: fp}}, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := fp + "|3|true|  um\n\n  [... truncado em 1 linhas ...]\n"
+	if w.String() != want {
+		t.Errorf("got %q, esperava %q", w.String(), want)
+	}
+}
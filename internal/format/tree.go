@@ -0,0 +1,210 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/tmpl"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// treeNode é um diretório (ou arquivo, com leaf) da árvore de arquivos do
+// cabeçalho (--tree) e da navegação do formato html. Os filhos ficam na ordem
+// em que surgem: a árvore segue --order, e cada diretório aparece onde está o
+// primeiro de seus arquivos.
+type treeNode struct {
+	name string
+	kids []*treeNode
+	leaf bool
+	file *scan.FileMeta // arquivo emitido (nil para os ignorados)
+	mark string         // anotação do arquivo ("failed", "binário ignorado", ...)
+}
+
+// insert pendura leaf no caminho path (separado por "/"), criando os
+// diretórios que faltam.
+func (n *treeNode) insert(path string, leaf *treeNode) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for _, dir := range parts[:len(parts)-1] {
+		n = n.dir(dir)
+	}
+	leaf.name, leaf.leaf = parts[len(parts)-1], true
+	n.kids = append(n.kids, leaf)
+}
+
+func (n *treeNode) dir(name string) *treeNode {
+	for _, k := range n.kids {
+		if !k.leaf && k.name == name {
+			return k
+		}
+	}
+	k := &treeNode{name: name}
+	n.kids = append(n.kids, k)
+	return k
+}
+
+// collapsed junta os diretórios com um único subdiretório num só nó
+// ("internal/format") e devolve o nome e o último deles.
+func (n *treeNode) collapsed() (string, *treeNode) {
+	name := n.name
+	for len(n.kids) == 1 && !n.kids[0].leaf {
+		n = n.kids[0]
+		name += "/" + n.name
+	}
+	return name, n
+}
+
+// fileTree monta a árvore dos arquivos de info, com as falhas marcadas, e,
+// com --tree-skipped, dos binários e sensíveis ignorados.
+func fileTree(cfg cli.Config, info DocInfo) *treeNode {
+	failed := map[string]bool{}
+	for _, fe := range info.Failed {
+		failed[fe.Path] = true
+	}
+	root := &treeNode{}
+	for i := range info.Files {
+		leaf := &treeNode{file: &info.Files[i]}
+		if failed[info.Files[i].Path] {
+			leaf.mark = "failed"
+		}
+		root.insert(treePath(info.Files[i].Path, cfg), leaf)
+	}
+	if cfg.TreeSkipped {
+		for _, s := range info.Skipped {
+			mark := "binário ignorado"
+			if s.Reason != "binary" {
+				mark = "sensível ignorado: " + s.Rule
+			}
+			root.insert(treePath(s.Path, cfg), &treeNode{mark: mark})
+		}
+	}
+	return root
+}
+
+// treePath é o caminho de p na árvore: relativo à raiz (-p) que o contém e,
+// com mais de uma raiz, sob o nome dela.
+func treePath(p string, cfg cli.Config) string {
+	ps := util.ToSlash(p)
+	for _, r := range cfg.Paths {
+		abs, err := util.Abs(r)
+		if err != nil {
+			continue
+		}
+		root := strings.TrimSuffix(util.ToSlash(abs), "/")
+		rel, ok := strings.CutPrefix(ps, root+"/")
+		if !ok {
+			if ps != root {
+				continue
+			}
+			rel = path.Base(ps) // -p apontando para um arquivo
+		}
+		if len(cfg.Paths) > 1 {
+			rel = path.Base(root) + "/" + rel
+		}
+		return DisplayPath(rel, cfg)
+	}
+	return DisplayPath(p, cfg)
+}
+
+// writeTree escreve a árvore no estilo do tree(1): em plain como linhas de
+// comentário do cabeçalho, em markdown e fenced num bloco de texto.
+func writeTree(w io.Writer, cfg cli.Config, info DocInfo) error {
+	var lines []string
+	for _, k := range fileTree(cfg, info).kids {
+		if k.leaf {
+			lines = append(lines, treeLabel(k))
+			continue
+		}
+		name, d := k.collapsed()
+		lines = append(lines, name+"/")
+		lines = treeLines(lines, d.kids, "")
+	}
+	var b strings.Builder
+	if cfg.Format == "plain" {
+		b.WriteString("# Tree:\n")
+		for _, l := range lines {
+			b.WriteString("#   " + l + "\n")
+		}
+	} else {
+		fence := tmpl.Fence(strings.Join(lines, "\n"))
+		b.WriteString("\n## Tree\n\n" + fence + "text\n")
+		for _, l := range lines {
+			b.WriteString(l + "\n")
+		}
+		b.WriteString(fence + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func treeLines(lines []string, kids []*treeNode, indent string) []string {
+	for i, k := range kids {
+		branch, next := "├── ", "│   "
+		if i == len(kids)-1 {
+			branch, next = "└── ", "    "
+		}
+		if k.leaf {
+			lines = append(lines, indent+branch+treeLabel(k))
+			continue
+		}
+		name, d := k.collapsed()
+		lines = append(lines, indent+branch+name+"/")
+		lines = treeLines(lines, d.kids, indent+next)
+	}
+	return lines
+}
+
+func treeLabel(n *treeNode) string {
+	if n.mark != "" {
+		return n.name + " [" + n.mark + "]"
+	}
+	return n.name
+}
+
+// writeTOC escreve o sumário dos arquivos de info, na ordem de saída. Em
+// markdown cada item é um link para o "## path" do arquivo (as falhas também
+// têm o seu, com o erro).
+func writeTOC(w io.Writer, cfg cli.Config, info DocInfo) error {
+	var b strings.Builder
+	if cfg.Format == "markdown" {
+		b.WriteString("\n## Contents\n\n")
+		seen := map[string]int{}
+		for i, fm := range info.Files {
+			p := DisplayPath(fm.Path, cfg)
+			fmt.Fprintf(&b, "%d. [%s](#%s)\n", i+1, p, mdAnchor(p, seen))
+		}
+	} else {
+		fmt.Fprintf(&b, "# Contents (%d):\n", len(info.Files))
+		for i, fm := range info.Files {
+			fmt.Fprintf(&b, "#   %d. %s\n", i+1, DisplayPath(fm.Path, cfg))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mdAnchor devolve a âncora que os renderizadores de markdown (GitHub)
+// geram para o título heading: minúsculas, espaços viram "-", pontuação sai.
+// Títulos repetidos recebem "-1", "-2"... (seen conta as ocorrências).
+func mdAnchor(heading string, seen map[string]int) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	a := b.String()
+	n := seen[a]
+	seen[a]++
+	if n > 0 {
+		a = fmt.Sprintf("%s-%d", a, n)
+	}
+	return a
+}
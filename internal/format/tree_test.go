@@ -0,0 +1,114 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestHeaderTreeFollowsOrder(t *testing.T) {
+	cfg := cli.Config{Format: "plain", Paths: []string{"."}, Tree: true, TreeSkipped: true}
+	info := format.DocInfo{
+		Files: []scan.FileMeta{
+			{Path: "src/z.go"},
+			{Path: "README.md"},
+			{Path: "src/pkg/a.go"},
+			{Path: "src/b.go"},
+		},
+		Skipped: []scan.Skipped{{Path: "src/logo.png", Reason: "binary"}},
+	}
+	var w bytes.Buffer
+	if err := format.WriteDocHeaderInfo(&w, cfg, info); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# Tree:",
+		"#   src/",
+		"#   ├── z.go",
+		"#   ├── pkg/",
+		"#   │   └── a.go",
+		"#   ├── b.go",
+		"#   └── logo.png [binário ignorado]",
+		"#   README.md",
+	}, "\n")
+	if !strings.Contains(w.String(), want) {
+		t.Errorf("árvore:\n%s\nesperava:\n%s", w.String(), want)
+	}
+}
+
+func TestHeaderTOCAnchors(t *testing.T) {
+	cfg := cli.Config{Format: "markdown", Paths: []string{"."}, TOC: true}
+	info := format.DocInfo{Files: []scan.FileMeta{{Path: "cmd/app/main.go"}, {Path: "My Notes.md"}}}
+	var w bytes.Buffer
+	if err := format.WriteDocHeaderInfo(&w, cfg, info); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"1. [cmd/app/main.go](#cmdappmaingo)",
+		"2. [My Notes.md](#my-notesmd)",
+	} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("faltou %q:\n%s", want, w.String())
+		}
+	}
+}
+
+func TestHeaderTreeRelativeToRoot(t *testing.T) {
+	dir := t.TempDir()
+	cfg := cli.Config{Format: "plain", Paths: []string{dir}, Tree: true}
+	info := format.DocInfo{
+		Files:  []scan.FileMeta{{Path: filepath.Join(dir, "cmd", "main.go")}, {Path: filepath.Join(dir, "go.mod")}},
+		Failed: []*format.FileError{{Path: filepath.Join(dir, "go.mod"), Err: os.ErrPermission}},
+	}
+	var w bytes.Buffer
+	if err := format.WriteDocHeaderInfo(&w, cfg, info); err != nil {
+		t.Fatal(err)
+	}
+	want := "# Tree:\n#   cmd/\n#   └── main.go\n#   go.mod [failed]\n"
+	if !strings.Contains(w.String(), want) {
+		t.Errorf("árvore:\n%s\nesperava:\n%s", w.String(), want)
+	}
+}
+
+// O sumário lista só os arquivos que entraram no documento: escrito depois de
+// ProcessFiles (format.HeaderListsFiles), a partir de Metrics.Written.
+func TestHeaderTOCListsAdmittedFiles(t *testing.T) {
+	dir := t.TempDir()
+	body := strings.Repeat("linha de código\n", 20)
+	for _, name := range []string{"a.md", "b.md", "c.md", "d.md"} {
+		_ = os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644)
+	}
+	cfg := cli.Config{Paths: []string{dir}, Format: "markdown", Order: "path", TOC: true, MaxTokens: 150}
+	if !format.HeaderListsFiles(cfg) {
+		t.Fatal("--toc deveria adiar o cabeçalho")
+	}
+	files, _, err := scan.List(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc bytes.Buffer
+	m, err := format.ProcessFiles(context.TODO(), &doc, files, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Omitted) == 0 {
+		t.Fatal("o orçamento deveria omitir arquivos")
+	}
+	var head bytes.Buffer
+	if err := format.WriteDocHeaderInfo(&head, cfg, format.DocInfo{Files: m.Written}); err != nil {
+		t.Fatal(err)
+	}
+	links := strings.Count(head.String(), "](#")
+	headings := strings.Count(doc.String(), "\n## ")
+	if links != len(m.Written) || links != headings {
+		t.Fatalf("sumário com %d links para %d blocos (%d escritos):\n%s", links, headings, len(m.Written), head.String())
+	}
+}
@@ -0,0 +1,79 @@
+package format
+
+import "sync"
+
+// window é a janela de reordenação de ProcessFiles: limita quantos blocos
+// estão em voo (despachados e ainda não escritos) e quantos bytes eles
+// ocupam. O bloco mais antigo em voo sempre é o próximo a ser escrito, então
+// a janela nunca bloqueia quando está vazia e não há como travar.
+type window struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	slots int   // máximo de blocos em voo
+	limit int64 // teto de memória em bytes (0 = sem teto)
+
+	n      int
+	mem    int64
+	peak   int64
+	closed bool
+}
+
+func newWindow(slots int, limit int64) *window {
+	w := &window{slots: slots, limit: limit}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// acquire reserva um lugar para um bloco estimado em est bytes, esperando
+// enquanto a janela estiver cheia. Devolve false se a janela foi fechada.
+func (w *window) acquire(est int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for !w.closed && w.n > 0 && (w.n >= w.slots || (w.limit > 0 && w.mem+est > w.limit)) {
+		w.cond.Wait()
+	}
+	if w.closed {
+		return false
+	}
+	w.n++
+	w.charge(est)
+	return true
+}
+
+// adjust corrige a reserva quando o tamanho real do bloco é conhecido.
+func (w *window) adjust(delta int64) {
+	w.mu.Lock()
+	w.charge(delta)
+	w.mu.Unlock()
+}
+
+// release libera o lugar de um bloco já escrito que ocupava n bytes.
+func (w *window) release(n int64) {
+	w.mu.Lock()
+	w.n--
+	w.mem -= n
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+// close acorda quem espera em acquire; as próximas chamadas falham.
+func (w *window) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+// peakBytes devolve o maior volume reservado ao mesmo tempo.
+func (w *window) peakBytes() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.peak
+}
+
+func (w *window) charge(n int64) {
+	w.mem += n
+	if w.mem > w.peak {
+		w.peak = w.mem
+	}
+}
@@ -0,0 +1,260 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// Formato xml (-F xml): o documento é um elemento <documents> com o
+// cabeçalho, um <document> por arquivo e o rodapé, na convenção usada em
+// prompts:
+//
+//	<documents>
+//	<header generated="…" paths="…"/>
+//	<document index="0" path="a.go" size="24" hash="…" lines="2" tokens="9" mtime="…" ext="go">
+//	<source>a.go</source>
+//	<document_content><![CDATA[package a
+//	]]></document_content>
+//	</document>
+//	<footer skipped_binary="0" skipped_secret="0"/>
+//	</documents>
+//
+// Os atributos de <document> são os campos do registro json. Conteúdo e diff
+// vão em CDATA; um "]]>" no texto é partido entre duas seções.
+
+// writeXMLHeader abre o elemento raiz e escreve <header>.
+func writeXMLHeader(w io.Writer, cfg cli.Config, info DocInfo) error {
+	var b strings.Builder
+	roots := make([]string, len(cfg.Paths))
+	for i, r := range cfg.Paths {
+		roots[i] = DisplayPath(r, cfg)
+	}
+	b.WriteString("<documents>\n<header")
+	xmlAttr(&b, "generated", util.Now().Format("2006-01-02 15:04:05 -0700"))
+	xmlAttr(&b, "paths", strings.Join(roots, ":"))
+	if cfg.ExtCSV != "" {
+		xmlAttr(&b, "extensions", cfg.ExtCSV)
+	}
+	if cfg.Depth > 0 {
+		xmlAttr(&b, "max_depth", strconv.Itoa(cfg.Depth))
+	}
+	if info.Revision != "" {
+		xmlAttr(&b, "revision", info.Revision)
+	}
+	if info.Changes != "" {
+		xmlAttr(&b, "changes", info.Changes)
+	}
+	if cfg.PolicyHash == "" && len(info.Deleted) == 0 {
+		b.WriteString("/>\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	b.WriteString(">\n")
+	if cfg.PolicyHash != "" {
+		b.WriteString("<policy")
+		xmlAttr(&b, "hash", cfg.PolicyHash)
+		b.WriteString(">\n")
+		for _, f := range cfg.PolicyFiles {
+			xmlElem(&b, "file", DisplayPath(f, cfg))
+		}
+		b.WriteString("</policy>\n")
+	}
+	for _, d := range info.Deleted {
+		xmlElem(&b, "deleted", DisplayPath(d, cfg))
+	}
+	b.WriteString("</header>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeXMLFooter escreve <footer> e fecha o elemento raiz.
+func writeXMLFooter(w io.Writer, cfg cli.Config, info FooterInfo) error {
+	var b strings.Builder
+	b.WriteString("<footer")
+	xmlAttr(&b, "skipped_binary", strconv.Itoa(info.SkippedBin))
+	xmlAttr(&b, "skipped_secret", strconv.Itoa(info.SkippedSecret))
+	if info.Tokenizer != "" {
+		xmlAttr(&b, "tokenizer", info.Tokenizer)
+		xmlAttr(&b, "tokens", strconv.Itoa(info.Tokens))
+	}
+	if cfg.MaxTokens > 0 {
+		xmlAttr(&b, "max_tokens", strconv.Itoa(cfg.MaxTokens))
+		xmlAttr(&b, "budget_tokens", strconv.Itoa(info.Budget))
+	}
+	b.WriteString(">\n")
+	for _, fe := range info.Failed {
+		b.WriteString("<failed")
+		xmlAttr(&b, "path", DisplayPath(fe.Path, cfg))
+		xmlAttr(&b, "error", fe.Reason())
+		b.WriteString("/>\n")
+	}
+	for _, f := range info.Redacted {
+		for _, it := range f.Items {
+			b.WriteString("<redaction")
+			xmlAttr(&b, "path", DisplayPath(f.Path, cfg))
+			xmlRedactionAttrs(&b, it)
+			b.WriteString("/>\n")
+		}
+	}
+	if cfg.MaxTokens > 0 {
+		if info.Cut != "" {
+			xmlElem(&b, "cut", DisplayPath(info.Cut, cfg))
+		}
+		for _, p := range info.Omitted {
+			xmlElem(&b, "omitted", DisplayPath(p, cfg))
+		}
+	}
+	b.WriteString("</footer>\n</documents>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderOneXML produz o <document> de um arquivo.
+func renderOneXML(fm scan.FileMeta, cfg cli.Config, src *source) (block, error) {
+	rec, written, err := fileRec(fm, cfg, src)
+	if err != nil || rec == nil {
+		return block{}, err
+	}
+	var b strings.Builder
+	xmlRecord(&b, rec)
+	out := b.String()
+	return block{buf: []byte(out), written: int64(written), tokens: blockTokens(cfg, out), content: rec.count, redactions: rec.Redactions}, nil
+}
+
+// xmlRecord escreve rec como <document>: metadados em atributos, conteúdo,
+// diff e redações em elementos filhos.
+func xmlRecord(b *strings.Builder, rec *jsonRec) {
+	b.WriteString("<document")
+	xmlAttr(b, "index", strconv.Itoa(rec.Index))
+	xmlAttr(b, "path", rec.Path)
+	xmlAttr(b, "size", strconv.FormatInt(rec.Size, 10))
+	if rec.Hash != "" {
+		xmlAttr(b, "hash", rec.Hash)
+	}
+	if rec.Error == "" {
+		xmlAttr(b, "lines", strconv.Itoa(rec.Lines))
+		if rec.Tokens != nil {
+			xmlAttr(b, "tokens", strconv.Itoa(*rec.Tokens))
+		}
+	}
+	xmlAttr(b, "mtime", strconv.FormatInt(rec.MTime, 10))
+	xmlAttr(b, "ext", rec.Ext)
+	if rec.Score != nil {
+		xmlAttr(b, "score", strconv.FormatFloat(*rec.Score, 'f', -1, 64))
+	}
+	if rec.Error != "" {
+		xmlAttr(b, "error", rec.Error)
+	}
+	b.WriteString(">\n")
+	xmlElem(b, "source", rec.Path)
+	if rec.Content != "" {
+		b.WriteString("<document_content>")
+		writeCDATA(b, rec.Content)
+		b.WriteString("</document_content>\n")
+	}
+	if rec.Diff != "" {
+		b.WriteString("<diff>")
+		writeCDATA(b, rec.Diff)
+		b.WriteString("</diff>\n")
+	}
+	for _, it := range rec.Redactions {
+		b.WriteString("<redaction")
+		xmlRedactionAttrs(b, it)
+		b.WriteString("/>\n")
+	}
+	b.WriteString("</document>\n")
+}
+
+func xmlRedactionAttrs(b *strings.Builder, it Redaction) {
+	xmlAttr(b, "placeholder", it.Placeholder)
+	xmlAttr(b, "rule", it.Rule)
+	xmlAttr(b, "line", strconv.Itoa(it.Line))
+	if it.Diff {
+		xmlAttr(b, "diff", "true")
+	}
+	xmlAttr(b, "fingerprint", it.Fingerprint)
+}
+
+// xmlAttr escreve ` name="value"` com o valor escapado.
+func xmlAttr(b *strings.Builder, name, value string) {
+	fmt.Fprintf(b, ` %s="`, name)
+	xmlEscape(b, value)
+	b.WriteByte('"')
+}
+
+// xmlElem escreve <name>text</name> numa linha.
+func xmlElem(b *strings.Builder, name, text string) {
+	fmt.Fprintf(b, "<%s>", name)
+	xmlEscape(b, text)
+	fmt.Fprintf(b, "</%s>\n", name)
+}
+
+// xmlEscape escapa s para texto ou atributo; caracteres que o XML 1.0 não
+// admite viram U+FFFD.
+func xmlEscape(b *strings.Builder, s string) {
+	for _, r := range s {
+		switch r {
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '&':
+			b.WriteString("&amp;")
+		case '"':
+			b.WriteString("&quot;")
+		case '\'':
+			b.WriteString("&apos;")
+		case '\t':
+			b.WriteString("&#x9;")
+		case '\n':
+			b.WriteString("&#xA;")
+		case '\r':
+			b.WriteString("&#xD;")
+		default:
+			b.WriteRune(xmlChar(r))
+		}
+	}
+}
+
+// writeCDATA escreve s em seções CDATA. Cada "]]>" fecha a seção entre "]]" e
+// ">", e caracteres proibidos viram U+FFFD (CDATA não tem escape).
+func writeCDATA(b *strings.Builder, s string) {
+	b.WriteString("<![CDATA[")
+	for {
+		i := strings.Index(s, "]]>")
+		if i < 0 {
+			break
+		}
+		writeXMLChars(b, s[:i+2])
+		b.WriteString("]]><![CDATA[")
+		s = s[i+2:]
+	}
+	writeXMLChars(b, s)
+	b.WriteString("]]>")
+}
+
+func writeXMLChars(b *strings.Builder, s string) {
+	for _, r := range s {
+		b.WriteRune(xmlChar(r))
+	}
+}
+
+// xmlChar devolve r, ou U+FFFD se r não é um caractere válido do XML 1.0
+// (controles, surrogates, UTF-8 inválido).
+func xmlChar(r rune) rune {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return r
+	case r < 0x20, r == utf8.RuneError, r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF:
+		return utf8.RuneError
+	default:
+		return r
+	}
+}
@@ -0,0 +1,66 @@
+package format_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestXMLDocumentsParse(t *testing.T) {
+	dir := t.TempDir()
+	body := "if a < b && x[y[0]]> 1 {\n\tprintln(\"]]>\")\x1b\n}\n"
+	fp := filepath.Join(dir, "a&b.go")
+	_ = os.WriteFile(fp, []byte(body), 0o644)
+
+	cfg := cli.Config{Format: "xml", Paths: []string{dir}}
+	var w bytes.Buffer
+	if err := format.WriteDocHeader(&w, cfg); err != nil {
+		t.Fatal(err)
+	}
+	files := []scan.FileMeta{{Path: fp, Size: int64(len(body)), Index: 0}}
+	if _, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := format.WriteSummaryFooter(&w, cfg, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Header struct {
+			Paths string `xml:"paths,attr"`
+		} `xml:"header"`
+		Documents []struct {
+			Path    string `xml:"path,attr"`
+			Lines   int    `xml:"lines,attr"`
+			Source  string `xml:"source"`
+			Content string `xml:"document_content"`
+		} `xml:"document"`
+		Footer struct {
+			SkippedSecret int `xml:"skipped_secret,attr"`
+		} `xml:"footer"`
+	}
+	if err := xml.Unmarshal(w.Bytes(), &doc); err != nil {
+		t.Fatalf("XML inválido: %v\n%s", err, w.String())
+	}
+	if doc.Header.Paths != dir || doc.Footer.SkippedSecret != 2 {
+		t.Errorf("cabeçalho/rodapé: %+v %+v", doc.Header, doc.Footer)
+	}
+	if len(doc.Documents) != 1 {
+		t.Fatalf("esperava 1 document, got %d", len(doc.Documents))
+	}
+	d := doc.Documents[0]
+	if d.Path != fp || d.Source != fp || d.Lines != 3 {
+		t.Errorf("metadados: %+v", d)
+	}
+	want := "if a < b && x[y[0]]> 1 {\n\tprintln(\"]]>\")�\n}\n"
+	if d.Content != want {
+		t.Errorf("conteúdo:\n%q\nesperava\n%q", d.Content, want)
+	}
+}
@@ -0,0 +1,67 @@
+package gitx
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestChangedModes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "t@t.t")
+	run("config", "user.name", "t")
+	write("keep.txt", "k\n")
+	write("gone.txt", "g\n")
+	write("edit.txt", "e\n")
+	run("add", ".")
+	run("commit", "-qm", "base")
+	run("checkout", "-qb", "feature")
+	write("edit.txt", "e2\n")
+	run("rm", "-q", "gone.txt")
+	run("commit", "-qam", "feature")
+	write("new.txt", "n\n")   // não rastreado
+	write("keep.txt", "k2\n") // alterado na árvore de trabalho
+	run("add", "keep.txt")    // e no índice
+
+	summary := func(spec ChangeSpec) string {
+		changes, err := Changed(dir, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, c := range changes {
+			out = append(out, c.Status+":"+filepath.Base(c.Path))
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	if got := summary(ChangeSpec{Staged: true}); got != "M:keep.txt" {
+		t.Fatalf("staged: %s", got)
+	}
+	if got := summary(ChangeSpec{Since: "HEAD"}); got != "A:new.txt,M:keep.txt" {
+		t.Fatalf("since HEAD: %s", got)
+	}
+	if got := summary(ChangeSpec{DiffBase: "main"}); got != "A:new.txt,D:gone.txt,M:edit.txt,M:keep.txt" {
+		t.Fatalf("diff-base main: %s", got)
+	}
+}
@@ -0,0 +1,76 @@
+package gitx
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Diff resolve a raiz, os arquivos rastreados e o merge-base uma vez por
+// repositório: cada arquivo custa só o próprio git diff.
+func TestDiffOneProcessPerFile(t *testing.T) {
+	gitBin, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git não encontrado")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("wrapper em shell")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command(gitBin, args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "t@t.t")
+	run("config", "user.name", "t")
+	names := []string{"a.txt", "b.txt", "c.txt"}
+	for _, n := range names {
+		write(n, n+"\n")
+	}
+	run("add", ".")
+	run("commit", "-qm", "base")
+	run("checkout", "-qb", "feature")
+	for _, n := range names {
+		write(n, n+" editado\n")
+	}
+	run("commit", "-qam", "feature")
+	write("novo.txt", "novo\n") // não rastreado
+
+	// wrapper que registra cada chamada ao git
+	bin := t.TempDir()
+	logf := filepath.Join(bin, "calls")
+	script := "#!/bin/sh\necho \"$*\" >> '" + logf + "'\nexec '" + gitBin + "' \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "git"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	spec := ChangeSpec{DiffBase: "main"}
+	for _, n := range append(names, "novo.txt") {
+		d, err := Diff(filepath.Join(dir, n), spec, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(d, "+"+strings.TrimSuffix(n, ".txt")) {
+			t.Fatalf("%s: hunks inesperados:\n%s", n, d)
+		}
+	}
+	data, _ := os.ReadFile(logf)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// rev-parse (raiz), ls-files e merge-base uma vez; um diff por arquivo
+	if want := len(names) + 1 + 3; len(calls) != want {
+		t.Fatalf("%d chamadas ao git, esperava %d:\n%s", len(calls), want, data)
+	}
+}
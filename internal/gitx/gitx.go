@@ -3,9 +3,12 @@ package gitx
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 func hasGit() bool {
@@ -73,3 +76,295 @@ func mapFiles(root string, lines []string) []string {
 	}
 	return files
 }
+
+// ChangeSpec escolhe o conjunto de arquivos alterados (no máximo um campo).
+type ChangeSpec struct {
+	Since    string // alterados desde a ref (commit vs. árvore de trabalho)
+	Staged   bool   // alterações no índice (git diff --cached)
+	DiffBase string // alterados desde o merge-base com a ref
+}
+
+// Active informa se algum modo de alterações foi pedido.
+func (s ChangeSpec) Active() bool {
+	return s.Since != "" || s.Staged || s.DiffBase != ""
+}
+
+// Describe resume o modo para o cabeçalho do documento.
+func (s ChangeSpec) Describe() string {
+	switch {
+	case s.Staged:
+		return "staged (git diff --cached)"
+	case s.Since != "":
+		return "since " + s.Since
+	case s.DiffBase != "":
+		return "diff-base " + s.DiffBase + " (merge-base)"
+	}
+	return ""
+}
+
+// Change é um arquivo alterado, com caminho absoluto.
+type Change struct {
+	Path    string
+	Status  string // A|M|D|R|C|T ("A" também para arquivos não rastreados)
+	OldPath string // origem de renomeações/cópias
+}
+
+// Deleted informa se o arquivo não existe mais do lado novo da comparação.
+func (c Change) Deleted() bool { return c.Status == "D" }
+
+// Changed lista os arquivos alterados sob path segundo spec.
+func Changed(path string, spec ChangeSpec) ([]Change, error) {
+	if !hasGit() || !isInsideRepo(path) {
+		return nil, errors.New("modo de alterações requer um repositório git")
+	}
+	root, err := RepoRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	rel, rerr := filepath.Rel(root, path)
+	if rerr != nil || rel == "" {
+		rel = "."
+	}
+
+	args := []string{"diff", "--name-status", "-z", "-M"}
+	untracked := true
+	switch {
+	case spec.Staged:
+		args = append(args, "--cached")
+		untracked = false
+	case spec.Since != "":
+		args = append(args, spec.Since)
+	case spec.DiffBase != "":
+		mb, err := repoFor(root).mergeBase(spec.DiffBase)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, mb)
+	default:
+		return nil, errors.New("nenhum modo de alterações informado")
+	}
+	args = append(args, "--", rel)
+	out, err := output(root, args...)
+	if err != nil {
+		return nil, err
+	}
+	changes := parseNameStatus(root, out)
+
+	if untracked {
+		out, err := output(root, "ls-files", "-o", "--exclude-standard", "-z", "--", rel)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range strings.Split(out, "\x00") {
+			if f != "" {
+				changes = append(changes, Change{Path: filepath.Join(root, f), Status: "A"})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// parseNameStatus interpreta a saída de `git diff --name-status -z`.
+func parseNameStatus(root, out string) []Change {
+	fields := strings.Split(out, "\x00")
+	var changes []Change
+	for i := 0; i < len(fields); i++ {
+		st := fields[i]
+		if st == "" {
+			continue
+		}
+		code := st[:1]
+		if (code == "R" || code == "C") && i+2 < len(fields) {
+			changes = append(changes, Change{
+				Path:    filepath.Join(root, fields[i+2]),
+				Status:  code,
+				OldPath: filepath.Join(root, fields[i+1]),
+			})
+			i += 2
+			continue
+		}
+		if i+1 < len(fields) {
+			changes = append(changes, Change{Path: filepath.Join(root, fields[i+1]), Status: code})
+			i++
+		}
+	}
+	return changes
+}
+
+func output(dir string, args ...string) (string, error) {
+	cmd := gitCmd(dir, args...)
+	var out, errb bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errb
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errb.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// repo guarda o que Diff e Changed resolvem uma vez por repositório durante
+// a execução: os caminhos rastreados e os merge-bases pedidos. Assim cada
+// arquivo custa um único processo git (o próprio diff).
+type repo struct {
+	root string
+
+	once    sync.Once
+	tracked map[string]bool // caminhos do índice, relativos à raiz
+	err     error
+
+	mu    sync.Mutex
+	bases map[string]string // ref -> merge-base com HEAD
+}
+
+var (
+	repoMu sync.Mutex
+	repos  = map[string]*repo{}  // raiz -> estado
+	roots  = map[string]string{} // diretório -> raiz do repositório
+)
+
+func repoFor(root string) *repo {
+	repoMu.Lock()
+	defer repoMu.Unlock()
+	r, ok := repos[root]
+	if !ok {
+		r = &repo{root: root, bases: map[string]string{}}
+		repos[root] = r
+	}
+	return r
+}
+
+// rootOf é RepoRoot com cache por diretório.
+func rootOf(dir string) (string, error) {
+	repoMu.Lock()
+	root, ok := roots[dir]
+	repoMu.Unlock()
+	if ok {
+		return root, nil
+	}
+	root, err := RepoRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	repoMu.Lock()
+	roots[dir] = root
+	repoMu.Unlock()
+	return root, nil
+}
+
+// isTracked informa se rel (relativo à raiz, com "/") está no índice.
+func (r *repo) isTracked(rel string) (bool, error) {
+	r.once.Do(func() {
+		out, err := output(r.root, "ls-files", "-z")
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.tracked = map[string]bool{}
+		for _, f := range strings.Split(out, "\x00") {
+			if f != "" {
+				r.tracked[f] = true
+			}
+		}
+	})
+	return r.tracked[rel], r.err
+}
+
+// mergeBase devolve o merge-base de ref com HEAD.
+func (r *repo) mergeBase(ref string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mb, ok := r.bases[ref]; ok {
+		return mb, nil
+	}
+	out, err := output(r.root, "merge-base", ref, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("merge-base %s: %w", ref, err)
+	}
+	mb := strings.TrimSpace(out)
+	r.bases[ref] = mb
+	return mb, nil
+}
+
+// Diff devolve os hunks do diff unificado de file (caminho absoluto) segundo
+// spec, com contextLines linhas de contexto. Sem modo ativo compara com HEAD.
+// Arquivos não rastreados aparecem inteiros como adição. O preâmbulo do patch
+// (diff --git/index/---/+++) é omitido; o caminho já está no cabeçalho. A
+// raiz, os arquivos rastreados e o merge-base de --diff-base são resolvidos
+// uma vez por repositório (veja repo).
+func Diff(file string, spec ChangeSpec, contextLines int) (string, error) {
+	if !hasGit() {
+		return "", errors.New("git não encontrado")
+	}
+	root, err := rootOf(filepath.Dir(file))
+	if err != nil {
+		return "", err
+	}
+	rp := repoFor(root)
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	unified := fmt.Sprintf("-U%d", contextLines)
+
+	tracked := spec.Staged
+	if !tracked {
+		if tracked, err = rp.isTracked(rel); err != nil {
+			return "", err
+		}
+	}
+	if !tracked {
+		// não rastreado: git diff --no-index sai com 1 quando há diferenças
+		cmd := gitCmd(root, "diff", "--no-index", "--no-color", unified, "--", os.DevNull, rel)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		if err := cmd.Run(); err != nil {
+			var ee *exec.ExitError
+			if !errors.As(err, &ee) || ee.ExitCode() != 1 {
+				return "", err
+			}
+		}
+		return Hunks(out.String()), nil
+	}
+
+	args := []string{"diff", "--no-color", "--no-ext-diff", unified}
+	switch {
+	case spec.Staged:
+		args = append(args, "--cached")
+	case spec.Since != "":
+		args = append(args, spec.Since)
+	case spec.DiffBase != "":
+		mb, err := rp.mergeBase(spec.DiffBase)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, mb)
+	default:
+		args = append(args, "HEAD")
+	}
+	out, err := output(root, append(args, "--", rel)...)
+	if err != nil {
+		return "", err
+	}
+	return Hunks(out), nil
+}
+
+// Hunks remove o preâmbulo de um patch, mantendo a partir do primeiro "@@"
+// (ou a linha "Binary files ... differ").
+func Hunks(patch string) string {
+	if i := strings.Index(patch, "\n@@"); i >= 0 {
+		return patch[i+1:]
+	}
+	if strings.HasPrefix(patch, "@@") {
+		return patch
+	}
+	for _, ln := range splitLines(patch) {
+		if strings.HasPrefix(ln, "Binary files ") {
+			return ln + "\n"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package gitx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tree é uma revisão lida direto do banco de objetos, sem checkout. Funciona
+// tanto em árvores de trabalho quanto em repositórios bare.
+type Tree struct {
+	Root   string // raiz da árvore de trabalho, ou o diretório do repositório bare
+	Rev    string // revisão pedida (tag, branch, SHA...)
+	Commit string // SHA completo do commit resolvido
+	Time   int64  // data do commit (unix), usada como mtime dos arquivos
+	Bare   bool
+
+	once    sync.Once
+	entries map[string]Entry // caminho absoluto -> entrada
+	err     error
+}
+
+// Entry é um blob da árvore.
+type Entry struct {
+	Path string // caminho absoluto (Root + caminho no repositório)
+	Blob string // SHA do blob
+	Size int64
+}
+
+// OpenRev resolve rev para um commit no repositório que contém path.
+func OpenRev(path, rev string) (*Tree, error) {
+	if !hasGit() {
+		return nil, errors.New("git não encontrado")
+	}
+	bare, err := output(path, "rev-parse", "--is-bare-repository")
+	if err != nil {
+		return nil, fmt.Errorf("--rev requer um repositório git: %w", err)
+	}
+	t := &Tree{Rev: rev, Bare: strings.TrimSpace(bare) == "true"}
+	if t.Bare {
+		dir, err := output(path, "rev-parse", "--absolute-git-dir")
+		if err != nil {
+			return nil, err
+		}
+		t.Root = strings.TrimSpace(dir)
+	} else if t.Root, err = RepoRoot(path); err != nil {
+		return nil, err
+	}
+	info, err := output(t.Root, "log", "-1", "--format=%H %ct", rev+"^{commit}", "--")
+	if err != nil {
+		return nil, fmt.Errorf("revisão %q: %w", rev, err)
+	}
+	fields := strings.Fields(info)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("revisão %q: saída inesperada do git log", rev)
+	}
+	t.Commit = fields[0]
+	t.Time, _ = strconv.ParseInt(fields[1], 10, 64)
+	return t, nil
+}
+
+// Short devolve o SHA abreviado do commit.
+func (t *Tree) Short() string {
+	if len(t.Commit) > 12 {
+		return t.Commit[:12]
+	}
+	return t.Commit
+}
+
+// List devolve os blobs abaixo de dir (absoluto), em ordem do git. Links
+// simbólicos e submódulos ficam de fora, como na varredura do disco.
+func (t *Tree) List(dir string) ([]Entry, error) {
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	dir = filepath.Clean(dir)
+	var res []Entry
+	for _, e := range t.sorted() {
+		if dir == t.Root || strings.HasPrefix(e.Path, dir+string(filepath.Separator)) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// Lookup procura o blob de um caminho absoluto.
+func (t *Tree) Lookup(abs string) (Entry, bool) {
+	if t.load() != nil {
+		return Entry{}, false
+	}
+	e, ok := t.entries[filepath.Clean(abs)]
+	return e, ok
+}
+
+// Read devolve o conteúdo do blob de abs.
+func (t *Tree) Read(abs string) ([]byte, error) {
+	e, ok := t.Lookup(abs)
+	if !ok {
+		return nil, fmt.Errorf("%s: ausente em %s", abs, t.Rev)
+	}
+	cmd := gitCmd(t.Root, "cat-file", "blob", e.Blob)
+	var out, errb bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errb
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git cat-file %s: %s", e.Blob, strings.TrimSpace(errb.String()))
+	}
+	return out.Bytes(), nil
+}
+
+func (t *Tree) load() error {
+	t.once.Do(func() {
+		out, err := output(t.Root, "ls-tree", "-r", "-z", "--long", "--full-tree", t.Commit)
+		if err != nil {
+			t.err = err
+			return
+		}
+		t.entries = map[string]Entry{}
+		for _, rec := range strings.Split(out, "\x00") {
+			// <modo> SP <tipo> SP <sha> SP+ <tamanho> TAB <caminho>
+			meta, name, ok := strings.Cut(rec, "\t")
+			if !ok {
+				continue
+			}
+			f := strings.Fields(meta)
+			if len(f) != 4 || f[1] != "blob" || f[0] == "120000" {
+				continue
+			}
+			size, _ := strconv.ParseInt(f[3], 10, 64)
+			abs := filepath.Join(t.Root, filepath.FromSlash(name))
+			t.entries[abs] = Entry{Path: abs, Blob: f[2], Size: size}
+		}
+	})
+	return t.err
+}
+
+func (t *Tree) sorted() []Entry {
+	paths := make([]string, 0, len(t.entries))
+	for p := range t.entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	out := make([]Entry, len(paths))
+	for i, p := range paths {
+		out[i] = t.entries[p]
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+// Package ignore avalia arquivos de ignore hierárquicos (.gitignore,
+// .codectxignore) sem depender do binário git.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/harrison-m-freitas/codectx/internal/match"
+)
+
+// Nomes dos arquivos de ignore reconhecidos.
+const (
+	GitIgnore     = ".gitignore"
+	CodectxIgnore = ".codectxignore"
+)
+
+// Hit descreve a regra que decidiu o resultado de Matcher.Check.
+type Hit struct {
+	Ignored bool
+	File    string // arquivo de ignore de onde veio o padrão
+	Pattern string
+}
+
+type source struct {
+	file string
+	list match.List
+}
+
+// Matcher avalia caminhos abaixo de root. Os arquivos de cada diretório são
+// lidos sob demanda e mantidos em cache; é seguro para uso concorrente.
+type Matcher struct {
+	root  string
+	names []string
+	read  func(path string) ([]byte, error)
+
+	mu   sync.Mutex
+	dirs map[string][]source // diretório -> padrões (na ordem de names)
+	memo map[string]Hit      // diretório -> resultado (para a poda)
+}
+
+// New cria um Matcher para root lendo, em cada diretório, os arquivos names
+// (os posteriores têm precedência sobre os anteriores no mesmo diretório).
+func New(root string, names ...string) *Matcher {
+	return NewReader(root, os.ReadFile, names...)
+}
+
+// NewReader é como New, mas lê os arquivos de ignore com read (por exemplo,
+// de uma revisão git em vez do disco).
+func NewReader(root string, read func(path string) ([]byte, error), names ...string) *Matcher {
+	return &Matcher{
+		root:  filepath.Clean(root),
+		names: names,
+		read:  read,
+		dirs:  map[string][]source{},
+		memo:  map[string]Hit{},
+	}
+}
+
+// Ignored informa se abs está ignorado.
+func (m *Matcher) Ignored(abs string, isDir bool) bool {
+	return m.Check(abs, isDir).Ignored
+}
+
+// Check segue a semântica do .gitignore: o arquivo de ignore mais profundo
+// vence, dentro de um arquivo o último padrão que casar vence e nada pode
+// ser reincluído se um diretório ancestral estiver ignorado.
+func (m *Matcher) Check(abs string, isDir bool) Hit {
+	abs = filepath.Clean(abs)
+	rel, err := filepath.Rel(m.root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return Hit{}
+	}
+	parent := filepath.Dir(abs)
+	if parent != m.root {
+		if h := m.checkDir(parent); h.Ignored {
+			return h
+		}
+	}
+	return m.checkSelf(abs, isDir)
+}
+
+func (m *Matcher) checkDir(dir string) Hit {
+	m.mu.Lock()
+	h, ok := m.memo[dir]
+	m.mu.Unlock()
+	if ok {
+		return h
+	}
+	h = m.Check(dir, true)
+	m.mu.Lock()
+	m.memo[dir] = h
+	m.mu.Unlock()
+	return h
+}
+
+// checkSelf avalia abs contra os arquivos de ignore da raiz até o seu pai.
+func (m *Matcher) checkSelf(abs string, isDir bool) Hit {
+	var chain []string
+	for d := filepath.Dir(abs); ; d = filepath.Dir(d) {
+		chain = append(chain, d)
+		if d == m.root || d == filepath.Dir(d) {
+			break
+		}
+	}
+	var res Hit
+	for i := len(chain) - 1; i >= 0; i-- {
+		dir := chain[i]
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, src := range m.load(dir) {
+			for _, p := range src.list {
+				if p.MatchSelf(rel, isDir) {
+					res = Hit{Ignored: !p.Negate, File: src.file, Pattern: p.Raw}
+				}
+			}
+		}
+	}
+	return res
+}
+
+func (m *Matcher) load(dir string) []source {
+	m.mu.Lock()
+	srcs, ok := m.dirs[dir]
+	m.mu.Unlock()
+	if ok {
+		return srcs
+	}
+	for _, n := range m.names {
+		fp := filepath.Join(dir, n)
+		lines, err := m.readLines(fp)
+		if err != nil {
+			continue
+		}
+		var list match.List
+		for _, ln := range lines {
+			if strings.TrimSpace(ln) == "" || strings.HasPrefix(ln, "#") {
+				continue
+			}
+			if p, err := match.Compile(ln, false); err == nil {
+				list = append(list, p) // padrões inválidos são ignorados, como no git
+			}
+		}
+		if len(list) > 0 {
+			srcs = append(srcs, source{file: fp, list: list})
+		}
+	}
+	m.mu.Lock()
+	m.dirs[dir] = srcs
+	m.mu.Unlock()
+	return srcs
+}
+
+func (m *Matcher) readLines(path string) ([]string, error) {
+	data, err := m.read(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		// espaços finais só contam se escapados com "\"
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		out = append(out, line)
+	}
+	return out, sc.Err()
+}
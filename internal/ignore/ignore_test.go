@@ -0,0 +1,67 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestHierarchicalIgnore(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, ".gitignore", "*.log\nbuild/\n!keep.log\n")
+	write(t, dir, "sub/.gitignore", "!debug.log\nlocal.txt\n")
+	write(t, dir, ".codectxignore", "docs/**\n")
+
+	m := New(dir, GitIgnore, CodectxIgnore)
+	cases := map[string]bool{
+		"a.log":          true,
+		"keep.log":       false,
+		"sub/debug.log":  false, // arquivo mais profundo vence
+		"sub/other.log":  true,
+		"sub/local.txt":  true,
+		"local.txt":      false, // padrão só vale abaixo de sub/
+		"build/out.js":   true,
+		"x/build/out.js": true,
+		"build":          true,
+		"docs/guide.md":  true,
+		"src/main.go":    false,
+		"build/keep.log": true, // diretório ignorado não pode ser reincluído
+	}
+	for rel, want := range cases {
+		abs := filepath.Join(dir, filepath.FromSlash(rel))
+		isDir := rel == "build"
+		if got := m.Ignored(abs, isDir); got != want {
+			t.Fatalf("%s: ignored=%v, esperado %v", rel, got, want)
+		}
+	}
+
+	h := m.Check(filepath.Join(dir, "sub", "other.log"), false)
+	if h.Pattern != "*.log" || h.File != filepath.Join(dir, ".gitignore") {
+		t.Fatalf("hit inesperado: %+v", h)
+	}
+}
+
+func TestOnlyCodectxIgnore(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, ".gitignore", "*.log\n")
+	write(t, dir, ".codectxignore", "*.snap\n")
+	m := New(dir, CodectxIgnore)
+	if m.Ignored(filepath.Join(dir, "a.log"), false) {
+		t.Fatal(".gitignore não deveria ser lido")
+	}
+	if !m.Ignored(filepath.Join(dir, "a.snap"), false) {
+		t.Fatal(".codectxignore deveria ser aplicado")
+	}
+}
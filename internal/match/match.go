@@ -0,0 +1,203 @@
+// Package match implementa padrões no estilo .gitignore: "*", "?", classes
+// "[a-z]", "**" entre segmentos, "!" para negação, "/" final para diretórios
+// e "/" inicial (ou no meio) para ancorar o padrão na base.
+package match
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Pattern é um padrão compilado.
+type Pattern struct {
+	Raw      string // texto original, incluindo "!"
+	Negate   bool   // começa com "!"
+	DirOnly  bool   // termina com "/": casa apenas diretórios (e seu conteúdo)
+	Anchored bool   // contém "/" no início ou no meio: relativo à base
+
+	re *regexp.Regexp
+}
+
+// IsGlob informa se p usa sintaxe de padrão (curingas ou negação). Padrões
+// sem isso mantêm a semântica antiga de substring/segmento dos filtros.
+func IsGlob(p string) bool {
+	return strings.HasPrefix(p, "!") || strings.ContainsAny(p, "*?[")
+}
+
+var cache sync.Map // key -> Pattern
+
+type cacheKey struct {
+	raw  string
+	fold bool
+}
+
+// Compile compila raw; fold torna o padrão case-insensitive. Os resultados
+// ficam em cache, pois os mesmos padrões são avaliados para cada arquivo.
+func Compile(raw string, fold bool) (Pattern, error) {
+	k := cacheKey{raw, fold}
+	if v, ok := cache.Load(k); ok {
+		return v.(Pattern), nil
+	}
+	p := Pattern{Raw: raw}
+	s := strings.TrimSpace(raw)
+	if strings.HasPrefix(s, "!") {
+		p.Negate = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, `\!`) {
+		s = s[1:]
+	}
+	if strings.HasSuffix(s, "/") {
+		p.DirOnly = true
+		s = strings.TrimRight(s, "/")
+	}
+	if strings.HasPrefix(s, "/") {
+		p.Anchored = true
+		s = strings.TrimLeft(s, "/")
+	} else if strings.Contains(s, "/") && !strings.HasPrefix(s, "**/") {
+		p.Anchored = true
+	}
+
+	var b strings.Builder
+	if fold {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	if !p.Anchored && !strings.HasPrefix(s, "**/") {
+		b.WriteString("(?:.*/)?")
+	}
+	b.WriteString(translate(s))
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return Pattern{}, err
+	}
+	p.re = re
+	cache.Store(k, p)
+	return p, nil
+}
+
+// translate converte o glob em expressão regular (sem âncoras).
+func translate(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '*':
+			if i+1 < len(s) && s[i+1] == '*' {
+				atStart := i == 0 || s[i-1] == '/'
+				j := i + 2
+				atEnd := j == len(s)
+				if atStart && atEnd {
+					// "a/**" casa o diretório e tudo dentro; "**" sozinho casa tudo
+					if i == 0 {
+						b.WriteString(".*")
+					} else {
+						str := b.String()
+						b.Reset()
+						b.WriteString(strings.TrimSuffix(str, "/"))
+						b.WriteString("(?:/.*)?")
+					}
+					i = j - 1
+					continue
+				}
+				if atStart && s[j] == '/' {
+					b.WriteString("(?:.*/)?")
+					i = j
+					continue
+				}
+				b.WriteString("[^/]*")
+				i++
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(s[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := s[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(s[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Match informa se o padrão casa com rel (caminho com "/" relativo à base)
+// ou com algum diretório ancestral de rel — como no .gitignore, excluir um
+// diretório exclui todo o seu conteúdo. O resultado ignora Negate.
+func (p Pattern) Match(rel string, isDir bool) bool {
+	rel = strings.TrimPrefix(rel, "/")
+	if (!p.DirOnly || isDir) && p.re.MatchString(rel) {
+		return true
+	}
+	for i := len(rel) - 1; i > 0; i-- {
+		if rel[i] == '/' && p.re.MatchString(rel[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchSelf é como Match, mas não considera os diretórios ancestrais; usado
+// por quem já avalia cada nível separadamente (arquivos de ignore).
+func (p Pattern) MatchSelf(rel string, isDir bool) bool {
+	rel = strings.TrimPrefix(rel, "/")
+	return (!p.DirOnly || isDir) && p.re.MatchString(rel)
+}
+
+// List é uma sequência de padrões avaliada com "o último que casar vence".
+type List []Pattern
+
+// CompileList compila raws em ordem; entradas vazias e comentários "#" são
+// ignorados.
+func CompileList(raws []string, fold bool) (List, error) {
+	l := make(List, 0, len(raws))
+	for _, r := range raws {
+		r = strings.TrimSpace(r)
+		if r == "" || strings.HasPrefix(r, "#") {
+			continue
+		}
+		p, err := Compile(r, fold)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, p)
+	}
+	return l, nil
+}
+
+// Decide devolve o último padrão que casa com rel. hit=false se nenhum casou;
+// caso contrário o caminho está "selecionado" se !last.Negate.
+func (l List) Decide(rel string, isDir bool) (last Pattern, hit bool) {
+	for _, p := range l {
+		if p.Match(rel, isDir) {
+			last, hit = p, true
+		}
+	}
+	return last, hit
+}
+
+// HasNegation informa se algum padrão da lista usa "!".
+func (l List) HasNegation() bool {
+	for _, p := range l {
+		if p.Negate {
+			return true
+		}
+	}
+	return false
+}
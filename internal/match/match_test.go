@@ -0,0 +1,67 @@
+package match
+
+import "testing"
+
+func TestPatternMatch(t *testing.T) {
+	cases := []struct {
+		pat   string
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"*.pb.go", "api/v1/x.pb.go", false, true},
+		{"*.pb.go", "api/v1/x.go", false, false},
+		{"**/testdata/**", "pkg/testdata/in.txt", false, true},
+		{"**/testdata/**", "testdata/in.txt", false, true},
+		{"**/testdata/**", "pkg/testdata", true, true},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "x/docs/a.md", false, false}, // ancorado na base
+		{"/build", "build/out.js", false, true},
+		{"/build", "src/build/out.js", false, false},
+		{"vendor", "a/vendor/b/c.go", false, true}, // conteúdo de diretório
+		{"out/", "out", false, false},              // só diretórios
+		{"out/", "out/a.txt", false, true},
+		{"a/**/b.go", "a/b.go", false, true},
+		{"a/**/b.go", "a/x/y/b.go", false, true},
+		{"file?.[ch]", "src/file1.c", false, true},
+		{"file[!0-9].c", "file1.c", false, false},
+	}
+	for _, c := range cases {
+		p, err := Compile(c.pat, false)
+		if err != nil {
+			t.Fatalf("%s: %v", c.pat, err)
+		}
+		if got := p.Match(c.rel, c.isDir); got != c.want {
+			t.Fatalf("%q vs %q (dir=%v): got %v want %v", c.pat, c.rel, c.isDir, got, c.want)
+		}
+	}
+}
+
+func TestListLastMatchWins(t *testing.T) {
+	l, err := CompileList([]string{"vendor", "!vendor/keep/**", "vendor/keep/tmp.go"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{ // true = selecionado (excluído)
+		"vendor/x.go":        true,
+		"vendor/keep/a.go":   false,
+		"vendor/keep/tmp.go": true,
+		"main.go":            false,
+	}
+	for rel, want := range cases {
+		p, hit := l.Decide(rel, false)
+		if got := hit && !p.Negate; got != want {
+			t.Fatalf("%s: got %v want %v", rel, got, want)
+		}
+	}
+	if !l.HasNegation() {
+		t.Fatal("HasNegation deveria ser true")
+	}
+}
+
+func TestCaseFold(t *testing.T) {
+	p, _ := Compile("*.GO", true)
+	if !p.Match("a/b.go", false) {
+		t.Fatal("fold deveria casar sem diferenciar maiúsculas")
+	}
+}
@@ -0,0 +1,134 @@
+// Package rank ordena arquivos por relevância para uma consulta textual
+// (BM25 sobre termos extraídos de identificadores, comentários e caminhos).
+package rank
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Parâmetros usuais do BM25.
+const (
+	k1 = 1.2
+	b  = 0.75
+
+	// pathWeight conta cada termo do caminho como várias ocorrências: o nome
+	// do arquivo costuma dizer mais sobre o assunto do que uma menção no corpo.
+	pathWeight = 3
+)
+
+// Doc é a representação de um arquivo no índice: frequência de cada termo.
+type Doc struct {
+	tf  map[string]int
+	len int
+}
+
+// NewDoc indexa o caminho relativo rel e o conteúdo do arquivo.
+func NewDoc(rel string, content []byte) Doc {
+	d := Doc{tf: map[string]int{}}
+	for _, t := range Terms(rel) {
+		d.tf[t] += pathWeight
+		d.len += pathWeight
+	}
+	for _, t := range Terms(string(content)) {
+		d.tf[t]++
+		d.len++
+	}
+	return d
+}
+
+// Score devolve a pontuação BM25 de cada documento para query, na ordem de
+// docs. As estatísticas (idf, tamanho médio) vêm do próprio conjunto.
+func Score(docs []Doc, query string) []float64 {
+	scores := make([]float64, len(docs))
+	q := unique(Terms(query))
+	if len(docs) == 0 || len(q) == 0 {
+		return scores
+	}
+	total := 0
+	for _, d := range docs {
+		total += d.len
+	}
+	avg := float64(total) / float64(len(docs))
+	if avg == 0 {
+		return scores
+	}
+	n := float64(len(docs))
+	for _, t := range q {
+		df := 0
+		for _, d := range docs {
+			if d.tf[t] > 0 {
+				df++
+			}
+		}
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+		for i, d := range docs {
+			f := float64(d.tf[t])
+			if f == 0 {
+				continue
+			}
+			scores[i] += idf * f * (k1 + 1) / (f + k1*(1-b+b*float64(d.len)/avg))
+		}
+	}
+	return scores
+}
+
+// Terms extrai os termos de s: sequências de letras e dígitos quebradas em
+// camelCase, snake_case, kebab-case e caminhos, em minúsculas. Termos de um
+// único caractere são descartados.
+func Terms(s string) []string {
+	var out []string
+	emit := func(w []rune) {
+		if len(w) > 1 {
+			out = append(out, strings.ToLower(string(w)))
+		}
+	}
+	var word []rune
+	rs := []rune(s)
+	for i, r := range rs {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			emit(word)
+			word = word[:0]
+			continue
+		}
+		if len(word) > 0 && boundary(rs, i) {
+			emit(word)
+			word = word[:0]
+		}
+		word = append(word, r)
+	}
+	emit(word)
+	return out
+}
+
+// boundary informa se rs[i] começa um novo termo dentro de um identificador:
+// "refreshToken" -> refresh|Token, "HTTPServer" -> HTTP|Server, "v2Api" ->
+// v|2|Api.
+func boundary(rs []rune, i int) bool {
+	prev, cur := rs[i-1], rs[i]
+	switch {
+	case unicode.IsDigit(prev) != unicode.IsDigit(cur):
+		return true
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(rs) && unicode.IsLower(rs[i+1]):
+		return true
+	}
+	return false
+}
+
+func unique(ts []string) []string {
+	seen := map[string]bool{}
+	out := ts[:0]
+	for _, t := range ts {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
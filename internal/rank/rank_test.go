@@ -0,0 +1,28 @@
+package rank_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/rank"
+)
+
+func TestTermsSplitsIdentifiers(t *testing.T) {
+	got := strings.Join(rank.Terms("refreshToken HTTPServer rotate_keys auth/token-store.go v2Api"), ",")
+	want := "refresh,token,http,server,rotate,keys,auth,token,store,go,api"
+	if got != want {
+		t.Fatalf("Terms=%s\nesperava %s", got, want)
+	}
+}
+
+func TestScoreRanksMatchingDocsFirst(t *testing.T) {
+	docs := []rank.Doc{
+		rank.NewDoc("docs/readme.md", []byte("how to install and configure the server")),
+		rank.NewDoc("auth/rotation.go", []byte("// RotateRefreshToken issues a new refresh token\nfunc RotateRefreshToken() {}")),
+		rank.NewDoc("auth/session.go", []byte("session cookies; token is validated elsewhere")),
+	}
+	sc := rank.Score(docs, "refresh token rotation")
+	if !(sc[1] > sc[2] && sc[2] > sc[0] && sc[0] == 0) {
+		t.Fatalf("ordem inesperada: %v", sc)
+	}
+}
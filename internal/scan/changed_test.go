@@ -0,0 +1,70 @@
+package scan_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestSinceListsChangedAndDeleted(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, data string) {
+		_ = os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644)
+	}
+	run("init", "-q")
+	run("config", "user.email", "t@t.t")
+	run("config", "user.name", "t")
+	write("a.go", "package a\n")
+	write("b.go", "package b\n")
+	write("c.md", "# c\n")
+	write("old.go", "package old\n")
+	run("add", ".")
+	run("commit", "-qm", "init")
+	write("a.go", "package a // v2\n")
+	write("c.md", "# c v2\n")
+	run("rm", "-q", "old.go")
+
+	cfg := cli.Config{
+		Paths:         []string{dir},
+		ExtCSV:        "go",
+		SecretsStrict: true,
+		BinarySkip:    true,
+		Order:         "path",
+		Since:         "HEAD",
+	}
+	files, cn, err := scan.List(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0].Path) != "a.go" {
+		t.Fatalf("esperava apenas a.go (c.md filtrado por ext), got %v", files)
+	}
+	if len(cn.Deleted) != 1 || !strings.HasSuffix(cn.Deleted[0], "/old.go") {
+		t.Fatalf("esperava old.go como removido, got %v", cn.Deleted)
+	}
+
+	var buf bytes.Buffer
+	_ = format.WriteDocHeaderInfo(&buf, cfg, format.DocInfo{Changes: scan.ChangeSpec(cfg).Describe(), Deleted: cn.Deleted})
+	if !strings.Contains(buf.String(), "# Changes: since HEAD") || !strings.Contains(buf.String(), "old.go") {
+		t.Fatalf("cabeçalho sem alterações/removidos:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,59 @@
+package scan_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestContentScanCountsRule(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "config.go"), []byte("package cfg\nconst k = \"AKIA"+"IOSFODNN7EXAMPLE\"\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "db.yaml"), []byte("url: postgres://app:"+"hunter22@db:5432/app\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, ".env"), []byte("X=1\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644)
+
+	// Fora do dry-run a listagem não lê o conteúdo: quem emite confere
+	cfg := cli.Config{Paths: []string{dir}, Order: "path", SecretsStrict: true, BinarySkip: true}
+	files, cn, err := scan.List(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || cn.SkippedSecret != 1 {
+		t.Fatalf("listagem: esperava 3 arquivos e 1 sensível (.env), got %d e %d", len(files), cn.SkippedSecret)
+	}
+
+	cfg.DryRun = true
+	files, cn, err = scan.List(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || cn.SkippedSecret != 3 {
+		t.Fatalf("esperava 1 arquivo e 3 sensíveis, got %d e %d", len(files), cn.SkippedSecret)
+	}
+	for _, id := range []string{"aws-access-key-id", "url-credentials", "dotenv"} {
+		if cn.SecretRules[id] != 1 {
+			t.Errorf("regra %s: %d, esperava 1 (%v)", id, cn.SecretRules[id], cn.SecretRules)
+		}
+	}
+
+	// o relatório guarda linha e fingerprint das ocorrências de conteúdo
+	var lines int
+	for _, h := range cn.SecretHits {
+		if h.Action != "skipped" {
+			t.Errorf("ação inesperada: %+v", h)
+		}
+		if h.Rule == "aws-access-key-id" && (h.Line != 2 || h.Fingerprint == "") {
+			t.Errorf("ocorrência sem linha/fingerprint: %+v", h)
+		}
+		lines += h.Line
+	}
+	if len(cn.SecretHits) != 3 || lines != 3 {
+		t.Fatalf("ocorrências inesperadas: %+v", cn.SecretHits)
+	}
+}
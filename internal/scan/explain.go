@@ -0,0 +1,203 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/filters"
+	"github.com/harrison-m-freitas/codectx/internal/gitx"
+	"github.com/harrison-m-freitas/codectx/internal/ignore"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/util"
+)
+
+// Explanation descreve por que um caminho entra (ou não) no pacote: cada
+// etapa do pipeline de List, na ordem em que é aplicada.
+type Explanation struct {
+	Path     string         `json:"path"`
+	Root     string         `json:"root,omitempty"`
+	Mode     string         `json:"mode,omitempty"` // git|walk
+	Steps    []filters.Step `json:"steps"`
+	Included bool           `json:"included"`
+	Reason   string         `json:"reason"` // primeira etapa reprovada, ou "ok"
+}
+
+// Explain passa target pelo mesmo pipeline de List e registra cada etapa.
+func Explain(ctx context.Context, cfg cli.Config, target string, log *logx.Logger) (*Explanation, error) {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return nil, err
+	}
+	ex := &Explanation{Path: util.ToSlash(abs)}
+	add := func(st filters.Step) {
+		ex.Steps = append(ex.Steps, st)
+		if st.Active && !st.Pass && ex.Reason == "" {
+			ex.Reason = st.Name
+		}
+	}
+
+	fi, err := os.Stat(abs)
+	if err != nil {
+		add(filters.Step{Name: "exists", Active: true, Pass: false, Detail: err.Error()})
+		return ex.finish(), nil
+	}
+	if !fi.Mode().IsRegular() {
+		add(filters.Step{Name: "exists", Active: true, Pass: false, Detail: "não é arquivo regular"})
+		return ex.finish(), nil
+	}
+
+	root := rootFor(abs, cfg.Paths)
+	if root == "" {
+		add(filters.Step{Name: "root", Active: true, Pass: false, Detail: "fora dos paths: " + strings.Join(cfg.Paths, ":")})
+		return ex.finish(), nil
+	}
+	ex.Root = util.ToSlash(root)
+	add(filters.Step{Name: "root", Active: true, Pass: true, Detail: ex.Root})
+
+	// Fonte da listagem: git ls-files ou varredura do FS (mesma regra de listPath)
+	if files, err := gitx.List(root); err == nil && len(files) > 0 {
+		ex.Mode = "git"
+		listed := false
+		for _, f := range absAll(files) {
+			if f == abs {
+				listed = true
+				break
+			}
+		}
+		detail := "git ls-files -co --exclude-standard"
+		if !listed {
+			detail += ": ausente (ignorado pelo .gitignore?)"
+		}
+		add(filters.Step{Name: "git", Active: true, Pass: listed, Detail: detail})
+		add(ignoreStep(ignoreMatcher(root, true), abs))
+		add(filters.Step{Name: "dir", Active: false, Pass: true, Detail: "não se aplica no modo git"})
+		add(filters.Step{Name: "depth", Active: false, Pass: true, Detail: "não se aplica no modo git"})
+	} else {
+		ex.Mode = "walk"
+		add(filters.Step{Name: "git", Active: false, Pass: true, Detail: "varredura do sistema de arquivos"})
+		add(ignoreStep(ignoreMatcher(root, false), abs))
+		add(excludedDirStep(root, abs, cfg))
+		add(depthStep(root, abs, cfg))
+	}
+
+	if spec := ChangeSpec(cfg); spec.Active() {
+		add(changedStep(root, abs, spec))
+	}
+
+	steps, _ := filters.Trace(abs, cfg)
+	for _, st := range steps {
+		add(st)
+	}
+
+	// Ordenação e corte por --max-files
+	if ex.Reason == "" {
+		st, err := cutoffStep(ctx, cfg, abs, log)
+		if err != nil {
+			return nil, err
+		}
+		add(st)
+	} else {
+		add(filters.Step{Name: "max-files", Active: false, Pass: true, Detail: "não avaliado (arquivo já descartado)"})
+	}
+	return ex.finish(), nil
+}
+
+func (ex *Explanation) finish() *Explanation {
+	ex.Included = ex.Reason == ""
+	if ex.Included {
+		ex.Reason = "ok"
+	}
+	return ex
+}
+
+func rootFor(abs string, paths []string) string {
+	for _, p := range paths {
+		root, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return ""
+}
+
+func changedStep(root, abs string, spec gitx.ChangeSpec) filters.Step {
+	changes, err := gitx.Changed(root, spec)
+	if err != nil {
+		return filters.Step{Name: "changed", Active: true, Pass: false, Detail: err.Error()}
+	}
+	for _, ch := range changes {
+		if p := absAll([]string{ch.Path}); len(p) > 0 && p[0] == abs {
+			return filters.Step{Name: "changed", Active: true, Pass: true, Detail: spec.Describe() + ": status " + ch.Status}
+		}
+	}
+	return filters.Step{Name: "changed", Active: true, Pass: false, Detail: spec.Describe() + ": sem alterações"}
+}
+
+func ignoreStep(m *ignore.Matcher, abs string) filters.Step {
+	h := m.Check(abs, false)
+	if h.Pattern == "" {
+		return filters.Step{Name: "ignore", Active: true, Pass: true}
+	}
+	detail := fmt.Sprintf("%s: %s", util.ToSlash(h.File), h.Pattern)
+	return filters.Step{Name: "ignore", Active: true, Pass: !h.Ignored, Detail: detail}
+}
+
+func excludedDirStep(root, abs string, cfg cli.Config) filters.Step {
+	var dirs []string
+	for d := filepath.Dir(abs); d != root && strings.HasPrefix(d, root); d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+	}
+	dirs = append(dirs, root)
+	slices.Reverse(dirs) // da raiz para baixo, como na varredura
+	for _, d := range dirs {
+		if ok, pat := filters.PolicyExcludedDir(d, cfg); ok {
+			return filters.Step{Name: "dir", Active: true, Pass: false, Detail: fmt.Sprintf("diretório podado na varredura: %s (política: %s)", dirRel(root, d), pat)}
+		}
+		if ok, pat := filters.ExcludedDir(dirRel(root, d), cfg); ok {
+			return filters.Step{Name: "dir", Active: true, Pass: false, Detail: fmt.Sprintf("diretório podado na varredura: %s (padrão: %s)", dirRel(root, d), pat)}
+		}
+	}
+	return filters.Step{Name: "dir", Active: true, Pass: true}
+}
+
+func depthStep(root, abs string, cfg cli.Config) filters.Step {
+	if cfg.Depth <= 0 {
+		return filters.Step{Name: "depth", Active: false, Pass: true}
+	}
+	rel := depthOf(abs) - depthOf(root)
+	return filters.Step{Name: "depth", Active: true, Pass: rel <= cfg.Depth, Detail: fmt.Sprintf("profundidade %d (máx. %d)", rel, cfg.Depth)}
+}
+
+func cutoffStep(ctx context.Context, cfg cli.Config, abs string, log *logx.Logger) (filters.Step, error) {
+	full := cfg
+	full.MaxFiles = 0
+	files, _, err := List(ctx, full, log)
+	if err != nil && !errors.Is(err, ErrMaxFilesExceeded) {
+		return filters.Step{}, err
+	}
+	pos := -1
+	for _, fm := range files {
+		if fm.Path == abs {
+			pos = fm.Index
+			break
+		}
+	}
+	if pos < 0 {
+		return filters.Step{Name: "max-files", Active: true, Pass: false, Detail: "ausente da listagem final"}, nil
+	}
+	detail := fmt.Sprintf("posição %d de %d (--order=%s)", pos+1, len(files), cfg.Order)
+	if cfg.MaxFiles <= 0 {
+		return filters.Step{Name: "max-files", Active: false, Pass: true, Detail: detail}, nil
+	}
+	return filters.Step{Name: "max-files", Active: true, Pass: pos < cfg.MaxFiles, Detail: fmt.Sprintf("%s, limite %d", detail, cfg.MaxFiles)}, nil
+}
@@ -0,0 +1,65 @@
+package scan_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestExplainReportsFailingStep(t *testing.T) {
+	dir := t.TempDir()
+	mk := func(p string) string {
+		fp := filepath.Join(dir, p)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, []byte("x\n"), 0o644)
+		return fp
+	}
+	ok1 := mk("a.go")
+	ok2 := mk("b.go")
+	sec := mk("auth/token.json")
+	pruned := mk("node_modules/m.go")
+
+	cfg := cli.Config{
+		Paths:         []string{dir},
+		ExtCSV:        "go,json",
+		Excludes:      []string{"node_modules"},
+		SecretsStrict: true,
+		BinarySkip:    true,
+		Order:         "path",
+		MaxFiles:      1,
+	}
+	log := logx.New()
+	cases := []struct {
+		path     string
+		included bool
+		reason   string
+	}{
+		{ok1, true, "ok"},
+		{ok2, false, "max-files"},
+		{sec, false, "secret"},
+		{pruned, false, "dir"},
+		{filepath.Join(dir, "missing.go"), false, "exists"},
+	}
+	for _, c := range cases {
+		ex, err := scan.Explain(context.TODO(), cfg, c.path, log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ex.Included != c.included || ex.Reason != c.reason {
+			t.Fatalf("%s: got included=%v reason=%s; want %v %s", c.path, ex.Included, ex.Reason, c.included, c.reason)
+		}
+	}
+
+	// o rastro registra a regra de segredo que casou
+	ex, _ := scan.Explain(context.TODO(), cfg, sec, log)
+	for _, st := range ex.Steps {
+		if st.Name == "secret" && st.Detail != "regra: substring:token" {
+			t.Fatalf("detalhe da regra inesperado: %q", st.Detail)
+		}
+	}
+}
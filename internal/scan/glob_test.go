@@ -0,0 +1,66 @@
+package scan_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func relPaths(t *testing.T, root string, files []scan.FileMeta) string {
+	t.Helper()
+	var out []string
+	for _, fm := range files {
+		rel, _ := filepath.Rel(root, fm.Path)
+		out = append(out, filepath.ToSlash(rel))
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
+
+func TestGlobExcludesSameInWalkAndGit(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"a.go", "gen/x.pb.go", "pkg/testdata/t.go", "pkg/testdata/keep.go", "pkg/b.go"} {
+		fp := filepath.Join(dir, p)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, []byte("package x\n"), 0o644)
+	}
+	cfg := cli.Config{
+		Paths:         []string{dir},
+		Excludes:      []string{".git", "*.pb.go", "**/testdata/**", "!**/keep.go"},
+		SecretsStrict: true,
+		BinarySkip:    true,
+		Order:         "path",
+	}
+	const want = "a.go,pkg/b.go,pkg/testdata/keep.go"
+	log := logx.New()
+
+	walk, _, err := scan.List(context.TODO(), cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := relPaths(t, dir, walk); got != want {
+		t.Fatalf("walk: got %s want %s", got, want)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	if err := exec.Command("git", "-C", dir, "init", "-q").Run(); err != nil {
+		t.Skip("git init falhou")
+	}
+	git, _, err := scan.List(context.TODO(), cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := relPaths(t, dir, git); got != want {
+		t.Fatalf("git: got %s want %s", got, want)
+	}
+}
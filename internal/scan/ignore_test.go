@@ -0,0 +1,61 @@
+package scan_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestIgnoreFilesSameInWalkAndGit(t *testing.T) {
+	dir := t.TempDir()
+	mk := func(p, data string) {
+		fp := filepath.Join(dir, p)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, []byte(data), 0o644)
+	}
+	mk(".gitignore", "*.log\ntmp/\n")
+	mk(".codectxignore", "*.snap\n.gitignore\n.codectxignore\n")
+	mk("a.go", "package a\n")
+	mk("debug.log", "x\n")
+	mk("tmp/cache.go", "package tmp\n")
+	mk("pkg/b.go", "package b\n")
+	mk("pkg/ui.snap", "snap\n")
+
+	cfg := cli.Config{
+		Paths:         []string{dir},
+		Excludes:      []string{".git"},
+		SecretsStrict: true,
+		BinarySkip:    true,
+		Order:         "path",
+	}
+	const want = "a.go,pkg/b.go"
+	log := logx.New()
+
+	walk, _, err := scan.List(context.TODO(), cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := relPaths(t, dir, walk); got != want {
+		t.Fatalf("walk: got %s want %s", got, want)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	if err := exec.Command("git", "-C", dir, "init", "-q").Run(); err != nil {
+		t.Skip("git init falhou")
+	}
+	git, _, err := scan.List(context.TODO(), cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := relPaths(t, dir, git); got != want {
+		t.Fatalf("git: got %s want %s", got, want)
+	}
+}
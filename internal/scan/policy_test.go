@@ -0,0 +1,61 @@
+package scan_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+// Os excludes da política são relativos ao diretório da política: -p num
+// subdiretório não os contorna, nem na varredura do FS nem no modo git.
+func TestPolicyExcludesAnchoredAtPolicyDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".codectx-policy.yaml":  "exclude: [\"customer-data/**\"]\n",
+		"svc/a.go":              "package svc\n",
+		"customer-data/eu/x.go": "package eu\n",
+	}
+	for p, body := range files {
+		fp := filepath.Join(root, p)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		if err := os.WriteFile(fp, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct{ path, want string }{
+		{root, "svc/a.go"},
+		{filepath.Join(root, "customer-data"), ""},
+		{filepath.Join(root, "customer-data", "eu"), ""},
+	}
+	log := logx.New()
+	run := func(mode string) {
+		for _, c := range cases {
+			cfg, _ := cli.Parse([]string{"-p", c.path, "--no-config", "-e", "go", "-O", "path"})
+			if err := cli.Validate(cfg); err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := scan.List(context.TODO(), cfg, log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := relPaths(t, root, got); s != c.want {
+				t.Errorf("%s -p %s: got %q want %q", mode, c.path, s, c.want)
+			}
+		}
+	}
+	run("walk")
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	if err := exec.Command("git", "-C", root, "init", "-q").Run(); err != nil {
+		t.Skip("git init falhou")
+	}
+	run("git")
+}
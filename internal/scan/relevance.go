@@ -0,0 +1,67 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/rank"
+)
+
+// rankByQuery indexa os arquivos selecionados (caminho relativo à raiz e
+// conteúdo) e preenche Score com a pontuação BM25 de cfg.Query. A leitura é
+// paralela, limitada por --jobs.
+func rankByQuery(files []FileMeta, cfg cli.Config) error {
+	docs := make([]rank.Doc, len(files))
+	errs := make([]error, len(files))
+	workers := cfg.Jobs
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			fm := files[i]
+			data, err := readContent(fm)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			docs[i] = rank.NewDoc(relPath(fm.Path, cfg.Paths), data)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	for i, sc := range rank.Score(docs, cfg.Query) {
+		files[i].Score = sc
+	}
+	return nil
+}
+
+// readContent lê o conteúdo de fm: o blob da revisão (--rev) ou o disco.
+func readContent(fm FileMeta) ([]byte, error) {
+	if fm.Rev != nil {
+		return fm.ReadBlob()
+	}
+	return os.ReadFile(fm.Path)
+}
+
+// relPath devolve path relativo à raiz (-p) que o contém, com "/".
+func relPath(path string, roots []string) string {
+	if root := rootFor(path, roots); root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,44 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestOrderRelevanceWithMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a/main.go":              "package main\n\nfunc main() {}\n",
+		"auth/refresh.go":        "package auth\n\n// rotateRefreshToken troca o refresh token\nfunc rotateRefreshToken() {}\n",
+		"auth/token_rotation.go": "package auth\n",
+		"docs/notes.md":          "nada a ver com o assunto\n",
+	}
+	for name, data := range files {
+		fp := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, []byte(data), 0o644)
+	}
+	cfg := cli.Config{
+		Paths:    []string{dir},
+		Order:    "relevance",
+		Query:    "refresh token rotation",
+		MaxFiles: 2,
+	}
+	got, _, err := scan.List(context.TODO(), cfg, logx.New())
+	if !errors.Is(err, scan.ErrMaxFilesExceeded) {
+		t.Fatalf("esperava corte por -N, got %v", err)
+	}
+	if len(got) != 2 || relPaths(t, dir, got) != "auth/refresh.go,auth/token_rotation.go" {
+		t.Fatalf("mais relevantes esperados primeiro: %s", relPaths(t, dir, got))
+	}
+	if got[0].Score < got[1].Score || got[1].Score <= 0 {
+		t.Fatalf("scores fora de ordem: %v %v", got[0].Score, got[1].Score)
+	}
+}
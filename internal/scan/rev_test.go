@@ -0,0 +1,107 @@
+package scan_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/format"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestRevReadsBlobsNotDisk(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	dir := t.TempDir()
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, data string) {
+		fp := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, []byte(data), 0o644)
+	}
+	run(dir, "init", "-q")
+	run(dir, "config", "user.email", "t@t.t")
+	run(dir, "config", "user.name", "t")
+	write("a.go", "package a\n")
+	write("pkg/b.go", "package b\n\nvar B = 1\n")
+	write("gone.go", "package gone\n")
+	write("img.bin", "x\x00y")
+	write(".codectxignore", "skip.go\n")
+	write("skip.go", "package skip\n")
+	run(dir, "add", ".")
+	run(dir, "commit", "-qm", "v1")
+	run(dir, "tag", "v1")
+	// árvore de trabalho suja: nada disso pode aparecer com --rev v1
+	write("a.go", "package a // editado\n")
+	write("new.go", "package novo\n")
+	run(dir, "rm", "-q", "gone.go")
+	run(dir, "commit", "-qm", "v2")
+
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	run(dir, "clone", "-q", "--bare", dir, bare)
+
+	for _, root := range []string{dir, bare} {
+		cfg := cli.Config{
+			Paths:      []string{root},
+			Format:     "plain",
+			BinarySkip: true,
+			Order:      "path",
+			Rev:        "v1",
+		}
+		files, cn, err := scan.List(context.TODO(), cfg, logx.New())
+		if err != nil {
+			t.Fatalf("%s: %v", root, err)
+		}
+		if got := relPaths(t, root, files); got != ".codectxignore,a.go,gone.go,pkg/b.go" {
+			t.Fatalf("%s: arquivos da revisão: %s", root, got)
+		}
+		if cn.SkippedBin != 1 {
+			t.Fatalf("%s: img.bin deveria ser binário pelo blob; SkippedBin=%d", root, cn.SkippedBin)
+		}
+
+		var w bytes.Buffer
+		if _, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil); err != nil {
+			t.Fatal(err)
+		}
+		out := w.String()
+		if strings.Contains(out, "editado") || !strings.Contains(out, "package a\n") {
+			t.Fatalf("%s: conteúdo deveria vir do blob:\n%s", root, out)
+		}
+		if !strings.Contains(out, "SIZE: 21 bytes | HASH: ") || !strings.Contains(out, "| LINES: 3\n") {
+			t.Fatalf("%s: tamanho/linhas deveriam vir do blob:\n%s", root, out)
+		}
+
+		// a emissão usa o blob lido pela seleção, sem outro git cat-file
+		for i := range files {
+			if files[i].Blob == nil {
+				t.Fatalf("%s: %s sem o blob da seleção", root, files[i].Path)
+			}
+			files[i].Blob = []byte("package lido // uma vez\n")
+		}
+		w.Reset()
+		if _, err := format.ProcessFiles(context.TODO(), &w, files, cfg, nil); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(w.String(), "uma vez") != len(files) {
+			t.Fatalf("%s: a emissão deveria reaproveitar o blob da seleção:\n%s", root, w.String())
+		}
+
+		trees, err := scan.Revisions(cfg)
+		if err != nil || len(trees) != 1 || len(trees[0].Commit) != 40 {
+			t.Fatalf("%s: revisão não resolvida: %v %v", root, trees, err)
+		}
+	}
+}
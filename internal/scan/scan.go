@@ -6,12 +6,16 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/harrison-m-freitas/codectx/internal/cli"
 	"github.com/harrison-m-freitas/codectx/internal/filters"
 	"github.com/harrison-m-freitas/codectx/internal/gitx"
+	"github.com/harrison-m-freitas/codectx/internal/ignore"
 	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
 	"github.com/harrison-m-freitas/codectx/internal/util"
 )
 
@@ -24,38 +28,333 @@ type FileMeta struct {
 	Ext   string
 	Key   string // chave de ordenação
 	Index int    // posição determinística pós-sort
+	Rev   *gitx.Tree // --rev: o conteúdo vem do blob desta revisão, não do disco
+	Blob  []byte     // --rev: o blob já lido pela seleção (nil se ela não o leu)
+	Score float64    // --order relevance: pontuação BM25 para --query
+}
+
+// ReadBlob devolve o conteúdo de fm na revisão de --rev: o que a seleção já
+// leu ou, se ela não precisou dele, uma leitura do blob.
+func (fm FileMeta) ReadBlob() ([]byte, error) {
+	if fm.Blob != nil {
+		return fm.Blob, nil
+	}
+	return fm.Rev.Read(fm.Path)
 }
 
 type Counters struct {
 	SkippedBin    int
 	SkippedSecret int
+	SecretRules   map[string]int // arquivos sensíveis por id de regra (nome ou conteúdo)
+	SecretHits    []secrets.Hit  // ocorrências dos arquivos sensíveis (--secrets-report)
 	TotalBytes    int64
+	Deleted       []string  // modo de alterações: removidos que passam pelos filtros
+	Skipped       []Skipped // binários e sensíveis ignorados, na ordem da listagem
+}
+
+// Skipped é um arquivo ignorado por ser binário ou sensível (--tree-skipped).
+type Skipped struct {
+	Path   string
+	Reason string // "binary" | "secret" | "secret-content"
+	Rule   string // regra de segredo, para os sensíveis
 }
 
 func List(ctx context.Context, cfg cli.Config, log *logx.Logger) ([]FileMeta, *Counters, error) {
-	var all []string
+	all, err := collect(cfg, log)
+	if err != nil {
+		return nil, nil, err
+	}
+	return selectFiles(all, cfg)
+}
+
+// Part agrupa os arquivos de um subdiretório de primeiro nível de uma raiz
+// (modo --split). Cada parte tem seus próprios contadores e limite de arquivos.
+type Part struct {
+	Name     string
+	Files    []FileMeta
+	Counters *Counters
+	Err      error // ErrMaxFilesExceeded quando a parte foi truncada
+}
+
+// RootPart nomeia a parte que reúne os arquivos soltos na raiz de um -p. Se a
+// raiz tiver um subdiretório com esse nome, a parte vira "_root_2" (ou o
+// primeiro sufixo livre).
+const RootPart = "_root"
+
+// ListSplit coleta os arquivos como List, mas os separa por subdiretório de
+// primeiro nível de cada raiz. Com mais de uma raiz, o nome da parte recebe o
+// nome da raiz como prefixo ("api/handlers"). Partes vazias são omitidas.
+func ListSplit(ctx context.Context, cfg cli.Config, log *logx.Logger) ([]Part, error) {
+	all, err := collect(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	// subdiretórios de primeiro nível de cada raiz, para o nome da parte solta
+	dirs := map[string]map[string]bool{}
+	for _, c := range all {
+		if top := topDir(c); top != "" {
+			if dirs[c.root] == nil {
+				dirs[c.root] = map[string]bool{}
+			}
+			dirs[c.root][top] = true
+		}
+	}
+	labels := rootLabels(all)
+	groups := map[string][]candidate{}
+	var names []string
+	for _, c := range all {
+		name := topDir(c)
+		if name == "" {
+			name = rootPartName(dirs[c.root])
+		}
+		if len(cfg.Paths) > 1 {
+			name = labels[c.root] + "/" + name
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], c)
+	}
+	sort.Strings(names)
+
+	parts := make([]Part, 0, len(names))
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		files, cn, err := selectFiles(groups[name], cfg)
+		if err != nil && !errors.Is(err, ErrMaxFilesExceeded) {
+			return nil, err
+		}
+		if len(files) == 0 && cn.SkippedBin == 0 && cn.SkippedSecret == 0 && len(cn.Deleted) == 0 {
+			continue
+		}
+		parts = append(parts, Part{Name: name, Files: files, Counters: cn, Err: err})
+	}
+	return parts, nil
+}
+
+type candidate struct {
+	path    string
+	root    string
+	deleted bool       // modo de alterações: removido do lado novo da comparação
+	tree    *gitx.Tree // --rev: revisão de onde o arquivo é lido
+	size    int64      // --rev: tamanho do blob
+}
+
+// ChangeSpec traduz as flags --since/--staged/--diff-base.
+func ChangeSpec(cfg cli.Config) gitx.ChangeSpec {
+	return gitx.ChangeSpec{Since: cfg.Since, Staged: cfg.Staged, DiffBase: cfg.DiffBase}
+}
+
+// Revisions resolve --rev para cada -p (em ordem, sem repetições). As
+// árvores ficam em cache para a listagem e a leitura dos blobs.
+func Revisions(cfg cli.Config) ([]*gitx.Tree, error) {
+	var out []*gitx.Tree
+	seen := map[*gitx.Tree]bool{}
+	for _, p := range cfg.Paths {
+		abs, _ := filepath.Abs(p)
+		t, err := openRev(abs, cfg.Rev)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+var (
+	revMu    sync.Mutex
+	revCache = map[[2]string]*gitx.Tree{} // {raiz do repo, rev} -> árvore
+)
+
+func openRev(path, rev string) (*gitx.Tree, error) {
+	t, err := gitx.OpenRev(path, rev)
+	if err != nil {
+		return nil, err
+	}
+	revMu.Lock()
+	defer revMu.Unlock()
+	k := [2]string{t.Root, t.Commit}
+	if c, ok := revCache[k]; ok {
+		return c, nil
+	}
+	revCache[k] = t
+	return t, nil
+}
+
+// collectRev lista os blobs de --rev abaixo de cada -p. Como no modo git,
+// --depth e a poda de diretórios não se aplicam; vale o .codectxignore da
+// própria revisão.
+func collectRev(cfg cli.Config, log *logx.Logger) ([]candidate, error) {
+	var all []candidate
+	for _, p := range cfg.Paths {
+		abs, _ := filepath.Abs(p)
+		t, err := openRev(abs, cfg.Rev)
+		if err != nil {
+			return nil, err
+		}
+		dir := abs
+		if t.Bare {
+			dir = t.Root
+		} else if real, err := filepath.EvalSymlinks(abs); err == nil {
+			dir = real
+		}
+		entries, err := t.List(dir)
+		if err != nil {
+			return nil, err
+		}
+		log.Debug("revisão %s (%s) em %s: %d arquivo(s)", t.Rev, t.Short(), abs, len(entries))
+		ign := ignore.NewReader(t.Root, t.Read, ignore.CodectxIgnore)
+		for _, e := range entries {
+			if !ign.Ignored(e.Path, false) {
+				all = append(all, candidate{path: e.Path, root: dir, tree: t, size: e.Size})
+			}
+		}
+	}
+	return all, nil
+}
+
+func collect(cfg cli.Config, log *logx.Logger) ([]candidate, error) {
+	if cfg.Rev != "" {
+		return collectRev(cfg, log)
+	}
+	spec := ChangeSpec(cfg)
+	var all []candidate
 	for _, p := range cfg.Paths {
 		abs, _ := filepath.Abs(p)
 		files, err := listPath(abs, cfg, log)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		all = append(all, files...)
+		if !spec.Active() {
+			for _, f := range files {
+				all = append(all, candidate{path: f, root: abs})
+			}
+			continue
+		}
+
+		// Modo de alterações: a listagem normal (ignores, poda) restrita aos
+		// arquivos alterados; removidos entram à parte.
+		changes, err := gitx.Changed(abs, spec)
+		if err != nil {
+			return nil, err
+		}
+		changed := map[string]bool{}
+		for _, ch := range changes {
+			ap := absAll([]string{ch.Path})
+			if len(ap) == 0 {
+				continue
+			}
+			if ch.Deleted() {
+				all = append(all, candidate{path: ap[0], root: abs, deleted: true})
+				continue
+			}
+			changed[ap[0]] = true
+		}
+		log.Debug("modo de alterações (%s) em %s: %d arquivo(s)", spec.Describe(), abs, len(changes))
+		for _, f := range files {
+			if changed[f] {
+				all = append(all, candidate{path: f, root: abs})
+			}
+		}
+	}
+	return all, nil
+}
+
+// topDir devolve o subdiretório de primeiro nível de c na sua raiz ("" para
+// arquivos soltos na raiz).
+func topDir(c candidate) string {
+	rel, err := filepath.Rel(c.root, c.path)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+	if i := strings.IndexByte(rel, '/'); i > 0 {
+		return rel[:i]
 	}
+	return ""
+}
+
+// rootLabels nomeia cada raiz pelo nome base, para o prefixo das partes com
+// mais de uma raiz; raízes de mesmo nome ("a/svc" e "b/svc") recebem "svc_2",
+// "svc_3"... na ordem dos -p, em vez de juntar seus arquivos na mesma parte.
+func rootLabels(all []candidate) map[string]string {
+	labels := map[string]string{}
+	used := map[string]bool{}
+	for _, c := range all {
+		if _, ok := labels[c.root]; ok {
+			continue
+		}
+		base := util.Base(c.root)
+		label := base
+		for n := 2; used[label]; n++ {
+			label = base + "_" + strconv.Itoa(n)
+		}
+		used[label] = true
+		labels[c.root] = label
+	}
+	return labels
+}
+
+// rootPartName escolhe o nome da parte solta sem colidir com os
+// subdiretórios da raiz.
+func rootPartName(dirs map[string]bool) string {
+	name := RootPart
+	for n := 2; dirs[name]; n++ {
+		name = RootPart + "_" + strconv.Itoa(n)
+	}
+	return name
+}
 
+func selectFiles(all []candidate, cfg cli.Config) ([]FileMeta, *Counters, error) {
 	cn := &Counters{}
 	selected := make([]FileMeta, 0, len(all))
-	for _, fp := range all {
+	for _, c := range all {
+		fp := c.path
+		if c.deleted {
+			if filters.DecidePath(fp, cfg).Include {
+				cn.Deleted = append(cn.Deleted, util.ToSlash(fp))
+			}
+			continue
+		}
+		if c.tree != nil {
+			cn.TotalBytes += c.size
+			var data []byte
+			if cfg.BinarySkip || cfg.SecretsStrict {
+				if b, err := c.tree.Read(fp); err == nil {
+					data = b
+				}
+			}
+			d := filters.DecideBlob(fp, c.size, data, cfg)
+			if !d.Include {
+				cn.Skip(fp, d)
+				continue
+			}
+			selected = append(selected, FileMeta{
+				Path:  fp,
+				Size:  c.size,
+				MTime: c.tree.Time,
+				Ext:   extLower(fp),
+				Rev:   c.tree,
+				Blob:  data, // a emissão não lê o blob de novo
+			})
+			continue
+		}
 		sz := util.FileSize(fp)
 		cn.TotalBytes += sz
-		d := filters.Decide(fp, cfg)
+		// O conteúdo é conferido por quem o lê para emitir (format), sem uma
+		// segunda leitura; o dry-run, que não lê, confere aqui.
+		decide := filters.DecideListed
+		if cfg.DryRun {
+			decide = filters.Decide
+		}
+		d := decide(fp, cfg)
 		if !d.Include {
-			switch d.Reason {
-			case "binary":
-				cn.SkippedBin++
-			case "secret":
-				cn.SkippedSecret++
-			}
+			cn.Skip(fp, d)
 			continue
 		}
 		selected = append(selected, FileMeta{
@@ -92,10 +391,27 @@ func List(ctx context.Context, cfg cli.Config, log *logx.Logger) ([]FileMeta, *C
 			}
 			return selected[i].MTime < selected[j].MTime
 		})
+	case "relevance":
+		if err := rankByQuery(selected, cfg); err != nil {
+			return nil, nil, err
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			if selected[i].Score == selected[j].Score {
+				return util.ToSlash(selected[i].Path) < util.ToSlash(selected[j].Path)
+			}
+			return selected[i].Score > selected[j].Score
+		})
 	default:
 		return nil, nil, errors.New("ordenação inválida")
 	}
 
+	// Fixados (--pin) vêm primeiro, preservando a ordem escolhida entre eles
+	if len(cfg.Pins) > 0 {
+		sort.SliceStable(selected, func(i, j int) bool {
+			return filters.Pinned(selected[i].Path, cfg) && !filters.Pinned(selected[j].Path, cfg)
+		})
+	}
+
   var limErr error
   if cfg.MaxFiles > 0 && len(selected) > cfg.MaxFiles {
     selected = selected[:cfg.MaxFiles]
@@ -105,9 +421,42 @@ func List(ctx context.Context, cfg cli.Config, log *logx.Logger) ([]FileMeta, *C
 	for i := range selected {
 		selected[i].Index = i
 	}
+	sort.Strings(cn.Deleted)
 	return selected, cn, limErr
 }
 
+// Skip registra path, descartado pela decisão d, nos contadores; também
+// usado para os arquivos que o format descarta ao ler (secret-content).
+func (cn *Counters) Skip(path string, d filters.Decision) {
+	switch d.Reason {
+	case "binary":
+		cn.SkippedBin++
+		cn.Skipped = append(cn.Skipped, Skipped{Path: util.ToSlash(path), Reason: d.Reason})
+	case "secret", "secret-content":
+		cn.SkippedSecret++
+		cn.Skipped = append(cn.Skipped, Skipped{Path: util.ToSlash(path), Reason: d.Reason, Rule: d.Rule})
+		if cn.SecretRules == nil {
+			cn.SecretRules = map[string]int{}
+		}
+		cn.SecretRules[d.Rule]++
+		cn.SecretHits = append(cn.SecretHits, skippedHits(path, d)...)
+	}
+}
+
+// skippedHits converte a decisão de um arquivo sensível em linhas do
+// relatório: uma por ocorrência no conteúdo, ou uma só para a regra de nome.
+func skippedHits(path string, d filters.Decision) []secrets.Hit {
+	path = util.ToSlash(path)
+	if len(d.Findings) == 0 {
+		return []secrets.Hit{{Path: path, Rule: d.Rule, Action: secrets.ActionSkipped}}
+	}
+	hits := make([]secrets.Hit, len(d.Findings))
+	for i, f := range d.Findings {
+		hits[i] = secrets.Hit{Path: path, Rule: f.Rule, Line: f.Line, Fingerprint: f.Fingerprint, Action: secrets.ActionSkipped}
+	}
+	return hits
+}
+
 func extLower(p string) string {
 	e := filepath.Ext(p)
 	if e == "" {
@@ -119,8 +468,16 @@ func extLower(p string) string {
 func listPath(path string, cfg cli.Config, log *logx.Logger) ([]string, error) {
 	if files, err := gitx.List(path); err == nil && len(files) > 0 {
 		log.Debug("git-aware ativo em: %s", path)
-		return absAll(files), nil
+		ign := ignoreMatcher(path, true)
+		var res []string
+		for _, f := range absAll(files) {
+			if !ign.Ignored(f, false) {
+				res = append(res, f)
+			}
+		}
+		return res, nil
 	}
+	ign := ignoreMatcher(path, false)
 
   var res []string
 	maxDepth := cfg.Depth
@@ -138,12 +495,12 @@ func listPath(path string, cfg cli.Config, log *logx.Logger) ([]string, error) {
 			return nil
 		}
 		if d.IsDir() {
-			if isExcludedDir(util.Base(p), cfg.Excludes, cfg.CaseInsensitive) {
+			if isExcludedDir(path, p, cfg) || ign.Ignored(p, true) {
         return filepath.SkipDir
       }
 			return nil
 		}
-		if !d.Type().IsRegular() {
+		if !d.Type().IsRegular() || ign.Ignored(p, false) {
 			return nil
 		}
 		res = append(res, p)
@@ -155,25 +512,37 @@ func listPath(path string, cfg cli.Config, log *logx.Logger) ([]string, error) {
 	return absAll(res), nil
 }
 
-func isExcludedDir(base string, excludes []string, insensitive bool) bool {
-  b := base
-  if insensitive {
-    b = strings.ToLower(b)
-  }
-	for _, ex := range excludes {
-		ex = strings.TrimSpace(ex)
-		if ex == "" || strings.ContainsAny(ex, "*/?/") {
-			continue
-	}
-    needle := ex
-    if insensitive {
-      needle = strings.ToLower(needle)
-    }
-		if b == needle {
-			return true
+// ignoreMatcher monta o matcher de arquivos de ignore para uma raiz. No modo
+// git o próprio git já aplica o .gitignore, então só o .codectxignore é lido,
+// a partir da raiz do repositório; na varredura do FS valem ambos, a partir
+// do -p.
+func ignoreMatcher(path string, git bool) *ignore.Matcher {
+	if git {
+		root, err := gitx.RepoRoot(path)
+		if err != nil {
+			root = path
 		}
+		return ignore.New(root, ignore.CodectxIgnore)
+	}
+	return ignore.New(path, ignore.GitIgnore, ignore.CodectxIgnore)
+}
+
+// isExcludedDir decide a poda de um diretório na varredura; a própria raiz é
+// comparada pelo nome base.
+func isExcludedDir(root, dir string, cfg cli.Config) bool {
+	if excluded, _ := filters.PolicyExcludedDir(dir, cfg); excluded {
+		return true
+	}
+	excluded, _ := filters.ExcludedDir(dirRel(root, dir), cfg)
+	return excluded
+}
+
+func dirRel(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return util.Base(dir)
 	}
-	return false
+	return filepath.ToSlash(rel)
 }
 
 func absAll(l []string) []string {
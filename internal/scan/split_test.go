@@ -0,0 +1,118 @@
+package scan_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/cli"
+	"github.com/harrison-m-freitas/codectx/internal/logx"
+	"github.com/harrison-m-freitas/codectx/internal/scan"
+)
+
+func TestListSplitGroupsByTopLevelDir(t *testing.T) {
+	dir := t.TempDir()
+	mk := func(p string, data []byte) {
+		fp := filepath.Join(dir, p)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, data, 0o644)
+	}
+	mk("README.md", []byte("# x\n"))
+	mk("svc-a/main.go", []byte("package a\n"))
+	mk("svc-a/internal/x.go", []byte("package x\n"))
+	mk("svc-b/b.go", []byte("package b\n"))
+	mk("svc-b/blob.bin", []byte{0, 1, 2})
+
+	cfg := cli.Config{
+		Paths:         []string{dir},
+		Excludes:      []string{},
+		Includes:      []string{},
+		SecretsStrict: true,
+		BinarySkip:    true,
+		Order:         "path",
+	}
+	parts, err := scan.ListSplit(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name  string
+		files int
+		bin   int
+	}{
+		{scan.RootPart, 1, 0},
+		{"svc-a", 2, 0},
+		{"svc-b", 1, 1},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("esperava %d partes, got %d", len(want), len(parts))
+	}
+	for i, w := range want {
+		p := parts[i]
+		if p.Name != w.name || len(p.Files) != w.files || p.Counters.SkippedBin != w.bin {
+			t.Fatalf("parte %d: got name=%s files=%d bin=%d; want %+v", i, p.Name, len(p.Files), p.Counters.SkippedBin, w)
+		}
+		for j, fm := range p.Files {
+			if fm.Index != j {
+				t.Fatalf("parte %s: índice %d esperado, got %d", p.Name, j, fm.Index)
+			}
+		}
+	}
+}
+
+func TestListSplitRootPartAvoidsDirName(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"loose.go", "_root/a.go", "_root_2/b.go"} {
+		fp := filepath.Join(dir, p)
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, []byte("package x\n"), 0o644)
+	}
+	cfg := cli.Config{Paths: []string{dir}, Excludes: []string{}, Includes: []string{}, Order: "path"}
+	parts, err := scan.ListSplit(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, p := range parts {
+		got[p.Name] = len(p.Files)
+	}
+	want := map[string]int{"_root": 1, "_root_2": 1, "_root_3": 1}
+	if len(got) != len(want) {
+		t.Fatalf("partes: got %v; want %v", got, want)
+	}
+	for n, c := range want {
+		if got[n] != c {
+			t.Fatalf("partes: got %v; want %v", got, want)
+		}
+	}
+	for _, p := range parts {
+		if p.Name == "_root_3" && filepath.Base(p.Files[0].Path) != "loose.go" {
+			t.Fatalf("parte solta deveria ser _root_3; got %s", p.Files[0].Path)
+		}
+	}
+}
+
+func TestListSplitSameNamedRoots(t *testing.T) {
+	base := t.TempDir()
+	a, b := filepath.Join(base, "a", "svc"), filepath.Join(base, "b", "svc")
+	for _, fp := range []string{filepath.Join(a, "api", "x.go"), filepath.Join(b, "api", "y.go")} {
+		_ = os.MkdirAll(filepath.Dir(fp), 0o755)
+		_ = os.WriteFile(fp, []byte("package api\n"), 0o644)
+	}
+	cfg := cli.Config{Paths: []string{a, b}, Excludes: []string{}, Includes: []string{}, Order: "path"}
+	parts, err := scan.ListSplit(context.TODO(), cfg, logx.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || parts[0].Name != "svc/api" || parts[1].Name != "svc_2/api" {
+		var names []string
+		for _, p := range parts {
+			names = append(names, p.Name)
+		}
+		t.Fatalf("raízes de mesmo nome deveriam gerar partes distintas: %v", names)
+	}
+	if filepath.Base(parts[1].Files[0].Path) != "y.go" {
+		t.Fatalf("svc_2 deveria ser a segunda raiz: %s", parts[1].Files[0].Path)
+	}
+}
@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"strings"
+
+	"github.com/harrison-m-freitas/codectx/internal/match"
+)
+
+// PathRule marca um arquivo como sensível pelo caminho, sem ler o conteúdo.
+// Os padrões seguem o .gitignore, sem diferenciar caixa, e casam o caminho do
+// próprio arquivo relativo à raiz (-p): um diretório inteiro é "**/.ssh/**".
+// Com Exts, a regra só vale para arquivos com uma dessas extensões ("" = um
+// dotfile sem extensão, como ".npmrc").
+type PathRule struct {
+	ID    string
+	Desc  string
+	Globs []string
+	Exts  []string
+
+	pats []match.Pattern
+}
+
+// dataExts são as extensões de arquivos de dados e configuração: um nome como
+// "api_token.json" sugere uma credencial, "tokenizer.go" não. O nome pode
+// estar no arquivo ou num diretório acima dele dentro da raiz
+// ("k8s/secrets/prod.yaml", um Secret do Kubernetes). Dotfiles sem extensão
+// (".secret_token") contam; código-fonte e os demais arquivos sem extensão
+// (Makefile, Dockerfile) ficam a cargo da varredura de conteúdo.
+var dataExts = []string{"", "json", "yaml", "yml", "toml", "ini", "cfg", "conf", "env", "properties", "txt", "xml"}
+
+// PathRules são as regras de nome embutidas, na ordem de avaliação.
+var PathRules = []PathRule{
+	{ID: "dir:.ssh", Desc: "chaves e configuração do SSH", Globs: []string{"**/.ssh/**"}},
+	{ID: "dir:.aws", Desc: "credenciais da AWS CLI", Globs: []string{"**/.aws/**"}},
+	{ID: "dir:.azure", Desc: "credenciais da Azure CLI", Globs: []string{"**/.azure/**"}},
+	{ID: "dir:.gnupg", Desc: "chaveiro do GnuPG", Globs: []string{"**/.gnupg/**"}},
+	{ID: "dir:.secrets", Desc: "diretório de segredos", Globs: []string{"**/.secrets/**"}},
+	{ID: "dotenv", Desc: "variáveis de ambiente (.env, .env.*)", Globs: []string{".env", ".env.*"}},
+	{ID: "ext:.pem", Desc: "certificado ou chave PEM", Globs: []string{"*.pem"}},
+	{ID: "ext:.key", Desc: "chave privada", Globs: []string{"*.key"}},
+	{ID: "ext:.p12", Desc: "contêiner PKCS#12", Globs: []string{"*.p12"}},
+	{ID: "ext:.pfx", Desc: "contêiner PKCS#12", Globs: []string{"*.pfx"}},
+	{ID: "ext:.asc", Desc: "chave ASCII-armored", Globs: []string{"*.asc"}},
+	{ID: "ext:.gpg", Desc: "arquivo GnuPG", Globs: []string{"*.gpg"}},
+	{ID: "ext:.kdbx", Desc: "banco do KeePass", Globs: []string{"*.kdbx"}},
+	{ID: "ext:.keystore", Desc: "keystore Java", Globs: []string{"*.keystore"}},
+	{ID: "ext:.jks", Desc: "keystore Java", Globs: []string{"*.jks"}},
+	{ID: "name:.git-credentials", Desc: "credenciais do git", Globs: []string{".git-credentials"}},
+	{ID: "name:.npmrc", Desc: "configuração do npm (tokens de registro)", Globs: []string{".npmrc"}},
+	{ID: "name:.pypirc", Desc: "configuração do PyPI", Globs: []string{".pypirc"}},
+	{ID: "name:.s3cfg", Desc: "configuração do s3cmd", Globs: []string{".s3cfg"}},
+	{ID: "name:.boto", Desc: "configuração do boto", Globs: []string{".boto"}},
+	{ID: "name:composer.auth.json", Desc: "credenciais do Composer", Globs: []string{"composer.auth.json"}},
+	{ID: "substring:secret", Desc: "arquivo de dados com \"secret\" no nome ou no diretório", Globs: []string{"*secret*", "**/*secret*/**"}, Exts: dataExts},
+	{ID: "substring:token", Desc: "arquivo de dados com \"token\" no nome ou no diretório", Globs: []string{"*token*", "**/*token*/**"}, Exts: dataExts},
+	{ID: "substring:apikey", Desc: "arquivo de dados com \"apikey\" no nome ou no diretório", Globs: []string{"*apikey*", "**/*apikey*/**"}, Exts: dataExts},
+	{ID: "substring:api_key", Desc: "arquivo de dados com \"api_key\" no nome ou no diretório", Globs: []string{"*api_key*", "**/*api_key*/**"}, Exts: dataExts},
+	{ID: "substring:password", Desc: "arquivo de dados com \"password\" no nome ou no diretório", Globs: []string{"*password*", "**/*password*/**"}, Exts: dataExts},
+	{ID: "substring:passwd", Desc: "arquivo de dados com \"passwd\" no nome ou no diretório", Globs: []string{"*passwd*", "**/*passwd*/**"}, Exts: dataExts},
+}
+
+func (r *PathRule) compile() error {
+	r.pats = nil
+	for _, g := range r.Globs {
+		p, err := match.Compile(g, true)
+		if err != nil {
+			return err
+		}
+		r.pats = append(r.pats, p)
+	}
+	exts := make([]string, len(r.Exts))
+	for i, e := range r.Exts {
+		exts[i] = strings.TrimPrefix(strings.ToLower(e), ".")
+	}
+	r.Exts = exts
+	return nil
+}
+
+// match informa se a regra casa o arquivo rel, relativo à raiz (-p), com "/".
+func (r *PathRule) match(rel string) bool {
+	if len(r.Exts) > 0 && !hasExt(rel, r.Exts) {
+		return false
+	}
+	return matchAny(r.pats, rel)
+}
+
+func matchAny(pats []match.Pattern, rel string) bool {
+	for _, p := range pats {
+		if !p.Negate && p.MatchSelf(rel, false) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExt(path string, exts []string) bool {
+	base := path[strings.LastIndexByte(path, '/')+1:]
+	i := strings.LastIndexByte(base, '.')
+	if i < 0 {
+		return false // sem extensão e sem ser dotfile
+	}
+	ext := ""
+	if i > 0 {
+		ext = strings.ToLower(base[i+1:])
+	}
+	for _, e := range exts {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Ações registradas no relatório.
+const (
+	ActionSkipped  = "skipped"  // o arquivo ficou fora do pacote
+	ActionRedacted = "redacted" // o segredo foi trocado por um marcador (--redact)
+)
+
+// Hit é uma linha do relatório de --secrets-report. Regras de nome não têm
+// linha nem fingerprint.
+type Hit struct {
+	Path        string `json:"path"`
+	Rule        string `json:"rule"`
+	Line        int    `json:"line,omitempty"`
+	Diff        bool   `json:"diff,omitempty"` // linha do diff, não do arquivo
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Action      string `json:"action"`
+}
+
+// ReportFormat escolhe o formato pelo nome do arquivo: "sarif" para *.sarif
+// e *.sarif.json, "json" para os demais.
+func ReportFormat(path string) string {
+	l := strings.ToLower(path)
+	if strings.HasSuffix(l, ".sarif") || strings.HasSuffix(l, ".sarif.json") {
+		return "sarif"
+	}
+	return "json"
+}
+
+// WriteReport escreve hits em w no formato dado ("sarif" ou "json"). As
+// descrições das regras vêm de rs.
+func WriteReport(w io.Writer, format string, hits []Hit, rs *RuleSet) error {
+	if hits == nil {
+		hits = []Hit{}
+	}
+	var v any
+	switch format {
+	case "json":
+		v = struct {
+			Findings []Hit `json:"findings"`
+		}{hits}
+	case "sarif":
+		v = sarifLog(hits, rs)
+	default:
+		return fmt.Errorf("formato de relatório inválido: %s", format)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Describe devolve a descrição da regra id ("" se desconhecida).
+func (rs *RuleSet) Describe(id string) string {
+	if id == EntropyRule {
+		return "literal de alta entropia atribuído a um nome de credencial"
+	}
+	for _, r := range rs.paths {
+		if r.ID == id {
+			return r.Desc
+		}
+	}
+	for _, r := range rs.content {
+		if r.ID == id {
+			return r.Desc
+		}
+	}
+	return ""
+}
+
+// Estruturas mínimas do SARIF 2.1.0 usadas pelo relatório.
+type (
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID              string            `json:"ruleId"`
+		RuleIndex           int               `json:"ruleIndex"`
+		Level               string            `json:"level"`
+		Message             sarifMessage      `json:"message"`
+		Locations           []sarifLocation   `json:"locations"`
+		PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+		Properties          map[string]any    `json:"properties"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysical `json:"physicalLocation"`
+	}
+	sarifPhysical struct {
+		ArtifactLocation sarifArtifact `json:"artifactLocation"`
+		Region           *sarifRegion  `json:"region,omitempty"`
+	}
+	sarifArtifact struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+func sarifLog(hits []Hit, rs *RuleSet) any {
+	ids := map[string]int{}
+	var names []string
+	for _, h := range hits {
+		if _, ok := ids[h.Rule]; !ok {
+			ids[h.Rule] = 0
+			names = append(names, h.Rule)
+		}
+	}
+	sort.Strings(names)
+	rules := make([]sarifRule, len(names))
+	for i, id := range names {
+		ids[id] = i
+		desc := rs.Describe(id)
+		if desc == "" {
+			desc = id
+		}
+		rules[i] = sarifRule{ID: id, ShortDescription: sarifMessage{Text: desc}}
+	}
+
+	results := make([]sarifResult, 0, len(hits))
+	for _, h := range hits {
+		loc := sarifPhysical{ArtifactLocation: sarifArtifact{URI: sarifURI(h.Path)}}
+		if h.Line > 0 && !h.Diff {
+			loc.Region = &sarifRegion{StartLine: h.Line}
+		}
+		msg := "arquivo sensível pelo nome (" + h.Rule + ")"
+		if h.Line > 0 {
+			msg = "segredo detectado (" + h.Rule + ")"
+		}
+		if h.Action == ActionSkipped {
+			msg += "; arquivo omitido do pacote"
+		} else {
+			msg += "; valor redigido no pacote"
+		}
+		r := sarifResult{
+			RuleID:     h.Rule,
+			RuleIndex:  ids[h.Rule],
+			Level:      "warning",
+			Message:    sarifMessage{Text: msg},
+			Locations:  []sarifLocation{{PhysicalLocation: loc}},
+			Properties: map[string]any{"action": h.Action},
+		}
+		if h.Fingerprint != "" {
+			r.PartialFingerprints = map[string]string{"codectx/value/v1": h.Fingerprint}
+		}
+		if h.Diff {
+			r.Properties["diffLine"] = h.Line
+		}
+		results = append(results, r)
+	}
+
+	return struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "codectx",
+				InformationURI: "https://github.com/harrison-m-freitas/codectx",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// sarifURI devolve caminhos relativos como URI relativa e absolutos como
+// file://.
+func sarifURI(p string) string {
+	if filepath.VolumeName(p) != "" {
+		p = "/" + p
+	}
+	if strings.HasPrefix(p, "/") {
+		return (&url.URL{Scheme: "file", Path: p}).String()
+	}
+	return (&url.URL{Path: p}).String()
+}
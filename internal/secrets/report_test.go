@@ -0,0 +1,74 @@
+package secrets_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
+)
+
+func TestWriteReportSARIF(t *testing.T) {
+	hits := []secrets.Hit{
+		{Path: "cmd/app/config.go", Rule: "aws-access-key-id", Line: 12, Fingerprint: "1a5d44a2dca19669", Action: secrets.ActionRedacted},
+		{Path: "deploy/.env", Rule: "dotenv", Action: secrets.ActionSkipped},
+	}
+	if secrets.ReportFormat("out/findings.SARIF") != "sarif" || secrets.ReportFormat("audit.json") != "json" {
+		t.Fatal("formato pelo nome do arquivo")
+	}
+	var buf bytes.Buffer
+	if err := secrets.WriteReport(&buf, "sarif", hits, secrets.Default()); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				PartialFingerprints map[string]string `json:"partialFingerprints"`
+				Properties          map[string]any    `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("estrutura inesperada:\n%s", buf.String())
+	}
+	run := log.Runs[0]
+	for _, r := range run.Results {
+		if run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID {
+			t.Errorf("ruleIndex %d não aponta para %s", r.RuleIndex, r.RuleID)
+		}
+	}
+	aws := run.Results[0]
+	loc := aws.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "cmd/app/config.go" || loc.Region == nil || loc.Region.StartLine != 12 {
+		t.Errorf("localização inesperada: %+v", loc)
+	}
+	if aws.PartialFingerprints["codectx/value/v1"] != "1a5d44a2dca19669" || aws.Properties["action"] != "redacted" {
+		t.Errorf("fingerprint/ação inesperados: %+v", aws)
+	}
+	if run.Results[1].Locations[0].PhysicalLocation.Region != nil {
+		t.Error("regra de nome não deveria ter região")
+	}
+}
@@ -0,0 +1,361 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harrison-m-freitas/codectx/internal/match"
+)
+
+// RuleSet são as regras em vigor: as embutidas (PathRules, Rules e a
+// heurística de entropia) mais o que um arquivo de regras acrescenta, desliga
+// ou libera. O arquivo tem este formato (YAML ou JSON, pela extensão):
+//
+//	paths:                       # regras de nome (mesmo id substitui a embutida)
+//	  - id: vault-export
+//	    globs: ["*.vault", "exports/**"]
+//	content:                     # regras de conteúdo
+//	  - id: acme-api-key
+//	    regex: 'acme_[0-9a-f]{32}'
+//	    keywords: [acme_]
+//	disable: [substring:token, high-entropy-string]
+//	allow:                       # cada entrada casa se todos os campos casarem
+//	  - paths: ["testdata/**"]
+//	  - paths: ["internal/auth/**"]
+//	    rules: [jwt]
+//	  - fingerprints: [3f9a0c1d2e4b5a67]
+type RuleSet struct {
+	Source string // arquivo de regras ("" = só as embutidas)
+
+	paths   []PathRule
+	content []Rule
+	entropy bool
+	allow   []allowEntry
+
+	roots sync.Map // raiz -> id da regra de diretório que a contém ("" se nenhuma)
+}
+
+type allowEntry struct {
+	pats         []match.Pattern
+	rules        map[string]bool
+	fingerprints map[string]bool
+}
+
+// rulesFile é o conteúdo de um arquivo de regras.
+type rulesFile struct {
+	Paths []struct {
+		ID    string   `yaml:"id" json:"id"`
+		Desc  string   `yaml:"desc" json:"desc"`
+		Globs []string `yaml:"globs" json:"globs"`
+		Exts  []string `yaml:"exts" json:"exts"`
+	} `yaml:"paths" json:"paths"`
+	Content []struct {
+		ID       string   `yaml:"id" json:"id"`
+		Desc     string   `yaml:"desc" json:"desc"`
+		Regex    string   `yaml:"regex" json:"regex"`
+		Keywords []string `yaml:"keywords" json:"keywords"`
+		Group    int      `yaml:"group" json:"group"`
+	} `yaml:"content" json:"content"`
+	Disable []string `yaml:"disable" json:"disable"`
+	Allow   []struct {
+		Paths        []string `yaml:"paths" json:"paths"`
+		Rules        []string `yaml:"rules" json:"rules"`
+		Fingerprints []string `yaml:"fingerprints" json:"fingerprints"`
+	} `yaml:"allow" json:"allow"`
+}
+
+var (
+	defaultOnce sync.Once
+	defaultSet  *RuleSet
+	loaded      sync.Map // arquivo -> *RuleSet
+)
+
+// Default devolve as regras embutidas.
+func Default() *RuleSet {
+	defaultOnce.Do(func() {
+		defaultSet = &RuleSet{content: Rules, entropy: true}
+		for _, r := range PathRules {
+			if err := r.compile(); err != nil {
+				panic(fmt.Sprintf("regra embutida %s: %v", r.ID, err))
+			}
+			defaultSet.paths = append(defaultSet.paths, r)
+		}
+	})
+	return defaultSet
+}
+
+// Load devolve as regras embutidas combinadas com o arquivo file ("" = só as
+// embutidas). O resultado fica em cache: o mesmo arquivo é consultado para
+// cada caminho avaliado.
+func Load(file string) (*RuleSet, error) {
+	if file == "" {
+		return Default(), nil
+	}
+	if v, ok := loaded.Load(file); ok {
+		return v.(*RuleSet), nil
+	}
+	rs, err := loadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("regras de segredo %s: %w", file, err)
+	}
+	loaded.Store(file, rs)
+	return rs, nil
+}
+
+func loadFile(file string) (*RuleSet, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rf rulesFile
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&rf)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&rf)
+		if errors.Is(err, io.EOF) {
+			err = nil // arquivo vazio
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	def := Default()
+	rs := &RuleSet{
+		Source:  file,
+		paths:   append([]PathRule(nil), def.paths...),
+		content: append([]Rule(nil), def.content...),
+		entropy: def.entropy,
+	}
+	for _, p := range rf.Paths {
+		r := PathRule{ID: p.ID, Desc: p.Desc, Globs: p.Globs, Exts: p.Exts}
+		if err := checkID(r.ID); err != nil {
+			return nil, fmt.Errorf("paths: %w", err)
+		}
+		if len(r.Globs) == 0 {
+			return nil, fmt.Errorf("paths: %s: informe ao menos um glob", r.ID)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("paths: %s: %w", r.ID, err)
+		}
+		rs.paths = replacePath(rs.paths, r)
+	}
+	for _, c := range rf.Content {
+		if err := checkID(c.ID); err != nil {
+			return nil, fmt.Errorf("content: %w", err)
+		}
+		re, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("content: %s: %w", c.ID, err)
+		}
+		if c.Group < 0 || c.Group > re.NumSubexp() {
+			return nil, fmt.Errorf("content: %s: group %d inexistente no regex", c.ID, c.Group)
+		}
+		kws := make([]string, len(c.Keywords))
+		for i, k := range c.Keywords {
+			kws[i] = strings.ToLower(k)
+		}
+		rs.content = replaceContent(rs.content, Rule{ID: c.ID, Desc: c.Desc, Keywords: kws, Re: re, Group: c.Group})
+	}
+	for _, id := range rf.Disable {
+		if !rs.known(id) {
+			return nil, fmt.Errorf("disable: regra desconhecida %q", id)
+		}
+		rs.disable(id)
+	}
+	for i, a := range rf.Allow {
+		if len(a.Paths)+len(a.Rules)+len(a.Fingerprints) == 0 {
+			return nil, fmt.Errorf("allow[%d]: entrada vazia", i)
+		}
+		e := allowEntry{}
+		for _, g := range a.Paths {
+			p, err := match.Compile(g, false)
+			if err != nil {
+				return nil, fmt.Errorf("allow[%d]: padrão %q: %w", i, g, err)
+			}
+			e.pats = append(e.pats, p)
+		}
+		if len(a.Rules) > 0 {
+			e.rules = map[string]bool{}
+			for _, id := range a.Rules {
+				if !rs.known(id) && !def.known(id) {
+					return nil, fmt.Errorf("allow[%d]: regra desconhecida %q", i, id)
+				}
+				e.rules[id] = true
+			}
+		}
+		if len(a.Fingerprints) > 0 {
+			e.fingerprints = map[string]bool{}
+			for _, fp := range a.Fingerprints {
+				e.fingerprints[strings.ToLower(fp)] = true
+			}
+		}
+		rs.allow = append(rs.allow, e)
+	}
+	return rs, nil
+}
+
+func checkID(id string) error {
+	if id == "" || strings.ContainsAny(id, " \t,") {
+		return fmt.Errorf("id inválido %q (sem espaços nem vírgulas)", id)
+	}
+	return nil
+}
+
+func replacePath(rules []PathRule, r PathRule) []PathRule {
+	for i := range rules {
+		if rules[i].ID == r.ID {
+			rules[i] = r
+			return rules
+		}
+	}
+	return append(rules, r)
+}
+
+func replaceContent(rules []Rule, r Rule) []Rule {
+	for i := range rules {
+		if rules[i].ID == r.ID {
+			rules[i] = r
+			return rules
+		}
+	}
+	return append(rules, r)
+}
+
+func (rs *RuleSet) known(id string) bool {
+	if id == EntropyRule {
+		return true
+	}
+	for _, r := range rs.paths {
+		if r.ID == id {
+			return true
+		}
+	}
+	for _, r := range rs.content {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (rs *RuleSet) disable(id string) {
+	if id == EntropyRule {
+		rs.entropy = false
+		return
+	}
+	paths := rs.paths[:0]
+	for _, r := range rs.paths {
+		if r.ID != id {
+			paths = append(paths, r)
+		}
+	}
+	rs.paths = paths
+	content := rs.content[:0]
+	for _, r := range rs.content {
+		if r.ID != id {
+			content = append(content, r)
+		}
+	}
+	rs.content = content
+}
+
+// MatchPath devolve o id da primeira regra de nome que marca o arquivo como
+// sensível ("" se nenhuma, ou se o allowlist o libera). rel é o caminho
+// relativo à raiz (-p) e full o caminho inteiro, ambos com "/". As regras
+// casam só rel: um diretório acima da raiz ("/srv/my-token-service") não
+// torna o repositório sensível. A exceção é -p apontando para dentro de um
+// diretório sensível (-p ~/.ssh), verificada uma vez por raiz (rootRule).
+func (rs *RuleSet) MatchPath(rel, full string) string {
+	if root, ok := strings.CutSuffix(full, "/"+rel); ok && rel != full {
+		if id := rs.rootRule(root); id != "" && !rs.allowed(rel, id, "") {
+			return id
+		}
+	}
+	for i := range rs.paths {
+		r := &rs.paths[i]
+		if r.match(rel) && !rs.allowed(rel, r.ID, "") {
+			return r.ID
+		}
+	}
+	return ""
+}
+
+// rootProbe é um nome fictício de arquivo dentro da raiz: uma regra sem Exts
+// que o casa ("**/.ssh/**") marca a raiz inteira. As regras com Exts (as de
+// substring) olham só para dentro da raiz.
+const rootProbe = "\x00"
+
+// rootRule devolve o id da regra de diretório que contém root ("" se nenhuma).
+func (rs *RuleSet) rootRule(root string) string {
+	if v, ok := rs.roots.Load(root); ok {
+		return v.(string)
+	}
+	id := ""
+	probe := strings.TrimPrefix(root, "/") + "/" + rootProbe
+	for i := range rs.paths {
+		if r := &rs.paths[i]; len(r.Exts) == 0 && matchAny(r.pats, probe) {
+			id = r.ID
+			break
+		}
+	}
+	rs.roots.Store(root, id)
+	return id
+}
+
+// Scan devolve as ocorrências em data que o allowlist não libera para o
+// arquivo rel (veja MatchPath).
+func (rs *RuleSet) Scan(rel string, data []byte) []Finding {
+	fs := scan(data, rs.content, rs.entropy)
+	if len(rs.allow) == 0 {
+		return fs
+	}
+	out := fs[:0]
+	for _, f := range fs {
+		if !rs.allowed(rel, f.Rule, f.Fingerprint) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// allowed informa se alguma entrada do allowlist casa. fp vazio (regras de
+// nome) nunca casa uma entrada com fingerprints.
+func (rs *RuleSet) allowed(rel, rule, fp string) bool {
+	for _, e := range rs.allow {
+		if len(e.pats) > 0 && !matchAny(e.pats, rel) {
+			continue
+		}
+		if e.rules != nil && !e.rules[rule] {
+			continue
+		}
+		if e.fingerprints != nil && !e.fingerprints[fp] {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Fingerprint identifica um valor sem expô-lo: os 16 primeiros dígitos hex
+// do SHA-256. É o que o allowlist compara em "fingerprints".
+func Fingerprint(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,145 @@
+package secrets_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
+)
+
+func TestDefaultPathRules(t *testing.T) {
+	rs := secrets.Default()
+	cases := map[string]string{
+		"internal/tokens/tokenizer.go":  "",
+		"api/password_reset_handler.go": "",
+		"internal/secrets/manager.go":   "",
+		"config/secrets.json":           "substring:secret",
+		"deploy/api_token.yaml":         "substring:token",
+		"deploy/api_token":              "",
+		"tokens/Makefile":               "",
+		".secret_token":                 "substring:secret",
+		".env.local":                    "dotenv",
+		"certs/Server.PEM":              "ext:.pem",
+		"home/.ssh/config":              "dir:.ssh",
+		"packages/web/.npmrc":           "name:.npmrc",
+		"docs/password-policy.md":       "",
+	}
+	for rel, want := range cases {
+		if got := rs.MatchPath(rel, "/repo/"+rel); got != want {
+			t.Errorf("%s: regra %q, esperava %q", rel, got, want)
+		}
+	}
+}
+
+// Casos do antigo DefaultSecretExcludes e da busca por substring no caminho
+// inteiro, que as regras embutidas precisam continuar cobrindo.
+func TestPathRulesCoverOldSecretExcludes(t *testing.T) {
+	rs := secrets.Default()
+	cases := []string{
+		".env", ".env.production", "config/.env.local",
+		"certs/server.pem", "tls/private.key", "certs/client.p12", "certs/client.pfx",
+		"keys/signing.asc", "backup/dump.gpg",
+		".git-credentials", ".npmrc", ".pypirc", ".s3cfg", ".boto",
+		".azure/credentials", ".aws/credentials", ".ssh/id_rsa", "home/.gnupg/pubring.kbx",
+		"vault.kdbx", "android/release.keystore", "app.jks",
+		"k8s/secrets/prod.yaml", "deploy/secret/db.json", "config/tokens/github.json",
+		"ops/passwords/db.txt", "ci/apikeys/stripe.env", "legacy/api_keys/list.xml",
+		"etc/passwd.d/users.conf",
+	}
+	for _, rel := range cases {
+		if got := rs.MatchPath(rel, "/repo/"+rel); got == "" {
+			t.Errorf("%s: deveria ser sensível", rel)
+		}
+	}
+	// -p dentro de um diretório sensível: a raiz é verificada uma vez
+	if got := rs.MatchPath("id_rsa", "/home/u/.ssh/id_rsa"); got != "dir:.ssh" {
+		t.Errorf("-p ~/.ssh: regra %q", got)
+	}
+}
+
+// Diretórios acima da raiz não contam para as regras de substring: um
+// repositório em /tmp/my-token-service não é inteiro sensível.
+func TestPathRulesIgnoreDirsAboveRoot(t *testing.T) {
+	rs := secrets.Default()
+	for _, rel := range []string{"Makefile", "config.yaml", "deploy/app.json", "README.md"} {
+		if got := rs.MatchPath(rel, "/tmp/my-token-service/"+rel); got != "" {
+			t.Errorf("%s: regra %q sob /tmp/my-token-service", rel, got)
+		}
+		if got := rs.MatchPath(rel, "/home/secrets/password-vault/"+rel); got != "" {
+			t.Errorf("%s: regra %q sob /home/secrets/password-vault", rel, got)
+		}
+	}
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	key := "AKIA" + "IOSFODNN7EXAMPLE"
+	fp := secrets.Fingerprint([]byte(key))
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	body := strings.Join([]string{
+		"paths:",
+		"  - id: vault-export",
+		"    globs: ['*.vault']",
+		"content:",
+		"  - id: acme-api-key",
+		"    regex: 'acme_([0-9a-f]{32})'",
+		"    keywords: [ACME_]",
+		"    group: 1",
+		"disable: [substring:token]",
+		"allow:",
+		"  - paths: ['testdata/**']",
+		"  - paths: ['internal/auth/**']",
+		"    rules: [jwt]",
+		"  - fingerprints: [" + fp + "]",
+	}, "\n")
+	if err := os.WriteFile(file, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := secrets.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rs.MatchPath("x/backup.vault", "/r/x/backup.vault"); got != "vault-export" {
+		t.Errorf("regra acrescentada: got %q", got)
+	}
+	if got := rs.MatchPath("deploy/api_token", "/r/deploy/api_token"); got != "" {
+		t.Errorf("regra desligada ainda casa: %q", got)
+	}
+	if got := rs.MatchPath("testdata/.env", "/r/testdata/.env"); got != "" {
+		t.Errorf("caminho liberado ainda casa: %q", got)
+	}
+
+	acme := []byte("k = acme_" + strings.Repeat("0f", 16) + "\n")
+	if fs := rs.Scan("a.go", acme); len(fs) != 1 || fs[0].Rule != "acme-api-key" {
+		t.Errorf("regra de conteúdo acrescentada: %+v", fs)
+	}
+	if fs := rs.Scan("a.go", []byte("id = "+key+"\n")); len(fs) != 0 {
+		t.Errorf("fingerprint liberado ainda reportado: %+v", fs)
+	}
+	jwt := []byte(`t := "eyJ` + `hbGciOiJIUzI1NiJ9.eyJzdWIiOiIxMjM0NTY3ODkwIn0.dozjgNryP4J3jVmNHl0w5N_XgL0n3I9PlFUP0THsR8U"` + "\n")
+	if fs := rs.Scan("internal/auth/x.go", jwt); len(fs) != 0 {
+		t.Errorf("regra liberada no caminho ainda reportada: %+v", fs)
+	}
+	if fs := rs.Scan("cmd/x.go", jwt); len(fs) != 1 {
+		t.Errorf("allowlist por regra vazou para outro caminho: %+v", fs)
+	}
+}
+
+func TestLoadRulesFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"unknown-key.yaml": "exclude: [x]\n",
+		"bad-regex.yaml":   "content:\n  - id: x\n    regex: '('\n",
+		"bad-disable.yaml": "disable: [nao-existe]\n",
+		"empty-allow.yaml": "allow:\n  - {}\n",
+		"bad-id.json":      `{"paths": [{"id": "a b", "globs": ["*.x"]}]}`,
+	} {
+		file := filepath.Join(dir, name)
+		_ = os.WriteFile(file, []byte(body), 0o644)
+		if _, err := secrets.Load(file); err == nil {
+			t.Errorf("%s: esperava erro", name)
+		}
+	}
+}
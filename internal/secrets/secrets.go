@@ -0,0 +1,255 @@
+// Package secrets reconhece arquivos sensíveis pelo caminho (PathRules) e
+// procura credenciais no conteúdo: formatos conhecidos (chaves de nuvem, PEM,
+// JWT, bearer, strings de conexão com senha) e uma heurística de entropia
+// para literais atribuídos a nomes como "token" ou "secret". Um arquivo de
+// regras (RuleSet) estende, desliga ou libera as regras embutidas.
+package secrets
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"sort"
+)
+
+// Rule é uma regra de conteúdo. O regex só roda quando alguma das palavras-
+// chave aparece no arquivo (sem diferenciar caixa), o que mantém a varredura
+// barata para a grande maioria dos arquivos.
+type Rule struct {
+	ID       string
+	Desc     string
+	Keywords []string
+	Re       *regexp.Regexp
+	Group    int // subgrupo com o segredo (0 = o match inteiro)
+}
+
+// Finding é uma ocorrência: regra, linha (a partir de 1), posição do
+// segredo em bytes e o Fingerprint do valor.
+type Finding struct {
+	Rule        string
+	Line        int
+	Start       int
+	End         int
+	Fingerprint string
+}
+
+// EntropyRule é o id da heurística de entropia.
+const EntropyRule = "high-entropy-string"
+
+// Rules são as regras de formato conhecido, na ordem de avaliação.
+var Rules = []Rule{
+	{
+		ID: "aws-access-key-id", Desc: "AWS access key ID",
+		Keywords: []string{"akia", "asia", "abia", "acca"},
+		Re:       regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`), Group: 1,
+	},
+	{
+		ID: "aws-secret-access-key", Desc: "AWS secret access key",
+		Keywords: []string{"aws"},
+		Re:       regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?['"\s:=]{1,5}([A-Za-z0-9/+=]{40})\b`), Group: 1,
+	},
+	{
+		ID: "gcp-api-key", Desc: "Google API key",
+		Keywords: []string{"aiza"},
+		Re:       regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})\b`), Group: 1,
+	},
+	{
+		ID: "github-token", Desc: "GitHub token",
+		Keywords: []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_"},
+		Re:       regexp.MustCompile(`\b((?:gh[pousr]_[A-Za-z0-9]{36,255})|(?:github_pat_[A-Za-z0-9_]{82}))\b`), Group: 1,
+	},
+	{
+		ID: "slack-token", Desc: "Slack token",
+		Keywords: []string{"xox"},
+		Re:       regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z-]{10,})\b`), Group: 1,
+	},
+	{
+		ID: "stripe-secret-key", Desc: "Stripe secret key",
+		Keywords: []string{"sk_live_", "rk_live_"},
+		Re:       regexp.MustCompile(`\b([sr]k_live_[0-9A-Za-z]{24,})\b`), Group: 1,
+	},
+	{
+		ID: "private-key", Desc: "chave privada PEM",
+		Keywords: []string{"private key"},
+		// o bloco inteiro quando há o END; senão só o cabeçalho
+		Re: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----(?:[\s\S]*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----)?`),
+	},
+	{
+		ID: "jwt", Desc: "JSON Web Token",
+		Keywords: []string{"eyj"},
+		Re:       regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`), Group: 1,
+	},
+	{
+		ID: "bearer-token", Desc: "token em cabeçalho Authorization: Bearer",
+		Keywords: []string{"bearer"},
+		Re:       regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{20,}=*)`), Group: 1,
+	},
+	{
+		ID: "url-credentials", Desc: "string de conexão com usuário e senha na URL",
+		Keywords: []string{"://"},
+		Re:       regexp.MustCompile(`://[^\s/:@'"]{1,64}:([^\s/:@'"]{3,128})@[A-Za-z0-9.\-]+`), Group: 1,
+	},
+	{
+		ID: "connection-string-password", Desc: "string de conexão com Password=",
+		Keywords: []string{"password=", "pwd="},
+		Re:       regexp.MustCompile(`(?i)(?:server|host|data source|user id|uid|database)=[^;'"\n]+;.*?\b(?:password|pwd)=([^;'"\s]{3,})`), Group: 1,
+	},
+}
+
+// Scan devolve as ocorrências em data pelas regras embutidas, ordenadas por
+// posição. Trechos sobrepostos (a mesma chave casada por duas regras)
+// aparecem uma vez, pela regra que vem primeiro em Rules.
+func Scan(data []byte) []Finding {
+	return scan(data, Rules, true)
+}
+
+func scan(data []byte, rules []Rule, withEntropy bool) []Finding {
+	lower := asciiLower(data)
+	var out []Finding
+	for _, r := range rules {
+		if len(r.Keywords) > 0 && !hasKeyword(lower, r.Keywords) {
+			continue
+		}
+		for _, m := range r.Re.FindAllSubmatchIndex(data, -1) {
+			start, end := m[2*r.Group], m[2*r.Group+1]
+			if start < 0 {
+				continue
+			}
+			out = append(out, Finding{Rule: r.ID, Start: start, End: end})
+		}
+	}
+	if withEntropy {
+		out = append(out, entropyFindings(data, lower)...)
+	}
+	return finish(data, out)
+}
+
+// First devolve a primeira ocorrência em data, se houver.
+func First(data []byte) (Finding, bool) {
+	fs := Scan(data)
+	if len(fs) == 0 {
+		return Finding{}, false
+	}
+	return fs[0], true
+}
+
+// asciiLower é bytes.ToLower só para ASCII: preserva os offsets de data.
+func asciiLower(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, c := range data {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func hasKeyword(lower []byte, kws []string) bool {
+	for _, k := range kws {
+		if bytes.Contains(lower, []byte(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish ordena, remove sobreposições e calcula linhas e fingerprints.
+func finish(data []byte, fs []Finding) []Finding {
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].Start < fs[j].Start })
+	out := fs[:0]
+	line, pos := 1, 0
+	for _, f := range fs {
+		if len(out) > 0 && f.Start < out[len(out)-1].End {
+			continue
+		}
+		line += bytes.Count(data[pos:f.Start], []byte{'\n'})
+		pos = f.Start
+		f.Line = line
+		f.Fingerprint = Fingerprint(data[f.Start:f.End])
+		out = append(out, f)
+	}
+	return out
+}
+
+// Heurística de entropia: literais entre aspas em linhas que mencionam nomes
+// de credencial, com entropia de Shannon alta para o alfabeto que usam.
+var (
+	entropyKeys = []string{
+		"secret", "token", "passw", "pwd", "credential", "auth",
+		"apikey", "api_key", "api-key", "accesskey", "access_key", "access-key",
+		"privatekey", "private_key", "private-key",
+	}
+	entropyLit = regexp.MustCompile(`["'` + "`" + `]([A-Za-z0-9+/_\-=.]{20,200})["'` + "`" + `]`)
+	hexOnly    = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	camelWord  = regexp.MustCompile(`[A-Z][a-z]{3,}`)
+)
+
+const (
+	minEntropy    = 4.0 // bits por caractere para literais base64/alfanuméricos
+	minHexEntropy = 3.0 // hex tem no máximo 4 bits por caractere
+	minHexLen     = 32
+)
+
+func entropyFindings(data, lower []byte) []Finding {
+	var out []Finding
+	for off := 0; off < len(data); {
+		end := bytes.IndexByte(data[off:], '\n')
+		if end < 0 {
+			end = len(data)
+		} else {
+			end += off + 1
+		}
+		if hasKeyword(lower[off:end], entropyKeys) {
+			for _, m := range entropyLit.FindAllSubmatchIndex(data[off:end], -1) {
+				if HighEntropy(string(data[off+m[2] : off+m[3]])) {
+					out = append(out, Finding{Rule: EntropyRule, Start: off + m[2], End: off + m[3]})
+				}
+			}
+		}
+		off = end
+	}
+	return out
+}
+
+// HighEntropy informa se s parece um segredo aleatório: hex longo ou
+// base64/alfanumérico com letras e dígitos misturados e entropia alta.
+// Identificadores em CamelCase ("DecryptPKCS1v15SessionKey") ficam de fora.
+func HighEntropy(s string) bool {
+	if hexOnly.MatchString(s) {
+		return len(s) >= minHexLen && entropy(s) >= minHexEntropy
+	}
+	if len(camelWord.FindAllStringIndex(s, 2)) == 2 {
+		return false
+	}
+	var upper, lowerc, digit bool
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lowerc = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lowerc && digit && entropy(s) >= minEntropy
+}
+
+// entropy é a entropia de Shannon de s em bits por caractere.
+func entropy(s string) float64 {
+	var freq [256]int
+	for i := 0; i < len(s); i++ {
+		freq[s[i]]++
+	}
+	h := 0.0
+	n := float64(len(s))
+	for _, c := range freq {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -0,0 +1,70 @@
+package secrets_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/secrets"
+)
+
+// Os exemplos são montados por concatenação para que este arquivo não seja,
+// ele mesmo, marcado pelo scanner.
+func TestScanRules(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		rule string
+	}{
+		{"aws id", `const key = "AKIA` + `IOSFODNN7EXAMPLE"`, "aws-access-key-id"},
+		{"aws secret", `aws_secret_access_key = wJalrXUtnFEMI/K7MDENG/` + `bPxRfiCYEXAMPLEKEY`, "aws-secret-access-key"},
+		{"gcp", `k := "AIza` + `SyD-1234567890abcdefghijklmnopqrstu"`, "gcp-api-key"},
+		{"github", `token: ghp_` + strings.Repeat("a1B2", 9), "github-token"},
+		{"pem", "-----BEGIN RSA " + "PRIVATE KEY-----\nMIIE...\n", "private-key"},
+		{"openssh", "-----BEGIN OPENSSH " + "PRIVATE KEY-----\n", "private-key"},
+		{"jwt", `Authorization = "eyJ` + `hbGciOiJIUzI1NiJ9.eyJzdWIiOiIxMjM0NTY3ODkwIn0.dozjgNryP4J3jVmNHl0w5N_XgL0n3I9PlFUP0THsR8U"`, "jwt"},
+		{"bearer", `curl -H "Authorization: Bearer ` + `abcdefghij0123456789KLMNOP"`, "bearer-token"},
+		{"url", `DATABASE_URL=postgres://app:` + `s3cr3t-pw@db.internal:5432/app`, "url-credentials"},
+		{"connstr", `"Server=tcp:db;Database=app;User Id=sa;` + `Password=Pa55word!;"`, "connection-string-password"},
+		{"entropy", `apiSecret := "Zx8` + `Qm2Lp9Rt4Vw7Yb1Nc6Hk3Jd5"`, secrets.EntropyRule},
+	}
+	for _, c := range cases {
+		f, ok := secrets.First([]byte("linha 1\n" + c.text + "\n"))
+		if !ok {
+			t.Errorf("%s: nada encontrado", c.name)
+			continue
+		}
+		if f.Rule != c.rule || f.Line != 2 {
+			t.Errorf("%s: regra %s linha %d, esperava %s linha 2", c.name, f.Rule, f.Line, c.rule)
+		}
+	}
+}
+
+func TestScanIgnoresOrdinaryCode(t *testing.T) {
+	clean := []string{
+		`tokenizer := "cl100k_base"`,
+		`url := "https://example.com/path?q=1"`,
+		`const DecryptName = "DecryptPKCS1v15SessionKey"`,
+		`password := os.Getenv("DB_PASSWORD")`,
+		`// aws region us-east-1`,
+		`sum := "h1:deadbeef"`,
+	}
+	for _, s := range clean {
+		if f, ok := secrets.First([]byte(s)); ok {
+			t.Errorf("falso positivo em %q: %s", s, f.Rule)
+		}
+	}
+}
+
+func TestScanOffsets(t *testing.T) {
+	key := "AKIA" + "IOSFODNN7EXAMPLE"
+	data := "a\nb\nid=" + key + " e de novo " + key + "\n"
+	fs := secrets.Scan([]byte(data))
+	if len(fs) != 2 {
+		t.Fatalf("esperava 2 ocorrências, got %v", fs)
+	}
+	for _, f := range fs {
+		if data[f.Start:f.End] != key || f.Line != 3 {
+			t.Fatalf("offset/linha errados: %+v", f)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package tmpl carrega os templates de -F template (text/template) e define
+// as funções auxiliares disponíveis neles.
+//
+// Um template define até três blocos, cada um executado com os dados
+// correspondentes do pacote format:
+//
+//	{{define "header"}}…{{end}}  uma vez, antes dos arquivos (format.TemplateDoc)
+//	{{define "file"}}…{{end}}    uma vez por arquivo (format.TemplateFile)
+//	{{define "footer"}}…{{end}}  uma vez, depois dos arquivos (format.TemplateFooter)
+//
+// Só "file" é obrigatório.
+package tmpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Funcs são as funções disponíveis nos templates.
+var Funcs = template.FuncMap{
+	"fence":  Fence,
+	"xml":    XMLEscape,
+	"json":   JSONString,
+	"indent": Indent,
+	"join":   strings.Join,
+	"lower":  strings.ToLower,
+	"upper":  strings.ToUpper,
+	"trim":   strings.TrimSpace,
+}
+
+var loaded sync.Map // arquivo -> *template.Template
+
+// Load lê e compila o template file; o resultado fica em cache, como o de
+// secrets.Load.
+func Load(file string) (*template.Template, error) {
+	if v, ok := loaded.Load(file); ok {
+		return v.(*template.Template), nil
+	}
+	t, err := template.New("").Funcs(Funcs).ParseFiles(file)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	if t.Lookup("file") == nil {
+		return nil, fmt.Errorf("template %s: defina o bloco {{define \"file\"}}", file)
+	}
+	loaded.Store(file, t)
+	return t, nil
+}
+
+// Fence devolve a cerca de bloco de código para body: três crases, ou uma a
+// mais que a maior sequência de crases em body, para que o conteúdo não
+// feche o bloco antes da hora.
+func Fence(body string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(body); i++ {
+		if body[i] == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+var xmlReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+// XMLEscape escapa s para texto ou atributo XML/HTML.
+func XMLEscape(s string) string { return xmlReplacer.Replace(s) }
+
+// JSONString devolve s como string JSON, com as aspas.
+func JSONString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // strings sempre codificam
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Indent prefixa com n espaços cada linha não vazia de s.
+func Indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			lines[i] = pad + l
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,165 @@
+// Package tokens conta tokens com tokenizadores BPE embutidos no binário
+// (vocabulários do tiktoken), sem acesso à rede.
+package tokens
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// Default é a codificação usada quando --tokenizer não é informado.
+const Default = "cl100k_base"
+
+// Encodings lista as codificações disponíveis.
+var Encodings = []string{"cl100k_base", "o200k_base", "p50k_base", "r50k_base"}
+
+// models mapeia nomes de modelo comuns para a codificação correspondente.
+var models = map[string]string{
+	"gpt-4o":        "o200k_base",
+	"gpt-4.1":       "o200k_base",
+	"o1":            "o200k_base",
+	"gpt-4":         "cl100k_base",
+	"gpt-3.5-turbo": "cl100k_base",
+}
+
+// Counter conta os tokens de um texto numa codificação. É seguro para uso
+// concorrente.
+type Counter struct {
+	name  string
+	codec tokenizer.Codec
+}
+
+var (
+	mu    sync.Mutex
+	cache = map[string]*Counter{}
+)
+
+// Get devolve o contador de name: uma codificação (cl100k_base...) ou um
+// nome de modelo (gpt-4o, gpt-4...). O vocabulário é carregado na primeira
+// chamada e reaproveitado.
+func Get(name string) (*Counter, error) {
+	enc, err := resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := cache[enc]; ok {
+		return c, nil
+	}
+	codec, err := tokenizer.Get(tokenizer.Encoding(enc))
+	if err != nil {
+		return nil, err
+	}
+	c := &Counter{name: enc, codec: codec}
+	cache[enc] = c
+	return c, nil
+}
+
+// Valid informa se name é aceito por Get, sem carregar o vocabulário.
+func Valid(name string) bool {
+	_, err := resolve(name)
+	return err == nil
+}
+
+func resolve(name string) (string, error) {
+	if name == "" {
+		return Default, nil
+	}
+	if enc, ok := models[name]; ok {
+		return enc, nil
+	}
+	for _, e := range Encodings {
+		if name == e {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("tokenizador desconhecido: %s (use %s ou gpt-4o|gpt-4)", name, strings.Join(Encodings, "|"))
+}
+
+// Name devolve o nome da codificação.
+func (c *Counter) Name() string { return c.name }
+
+// Estimated nomeia a contagem de Estimate nos resumos.
+const Estimated = "estimativa"
+
+// Estimate estima os tokens de s sem tokenizar: ~4 bytes por token, a média
+// dos tokenizadores BPE usuais para código.
+func Estimate(s string) int { return (len(s) + 3) / 4 }
+
+// Limites para linhas longas (arquivos minificados, dados embutidos): o BPE
+// é quadrático no tamanho de cada pedaço, e uma linha de centenas de KB sem
+// espaços levaria minutos. Linhas acima de longLine são contadas em fatias de
+// longSlice bytes; o erro fica em torno de um token por fatia.
+const (
+	longLine  = 4 << 10
+	longSlice = 256
+)
+
+// Count devolve o número de tokens de s.
+func (c *Counter) Count(s string) int {
+	if !hasLongLine(s) {
+		return c.encode(s)
+	}
+	n := 0
+	start := 0 // início das linhas curtas ainda não contadas
+	for i := 0; i < len(s); {
+		end := strings.IndexByte(s[i:], '\n')
+		if end < 0 {
+			end = len(s)
+		} else {
+			end += i + 1
+		}
+		if end-i > longLine {
+			n += c.encode(s[start:i])
+			for j := i; j < end; {
+				k := sliceEnd(s, j, end)
+				n += c.encode(s[j:k])
+				j = k
+			}
+			start = end
+		}
+		i = end
+	}
+	return n + c.encode(s[start:])
+}
+
+func (c *Counter) encode(s string) int {
+	if s == "" {
+		return 0
+	}
+	ids, _, err := c.codec.Encode(s)
+	if err != nil {
+		// o regex de pré-tokenização não deveria falhar; na dúvida, estima
+		return (len(s) + 3) / 4
+	}
+	return len(ids)
+}
+
+func hasLongLine(s string) bool {
+	for len(s) > longLine {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 || i > longLine {
+			return true
+		}
+		s = s[i+1:]
+	}
+	return false
+}
+
+// sliceEnd devolve o fim da fatia que começa em j, sem partir um caractere
+// UTF-8 ao meio.
+func sliceEnd(s string, j, end int) int {
+	k := j + longSlice
+	if k >= end {
+		return end
+	}
+	for k > j+1 && !utf8.RuneStart(s[k]) {
+		k--
+	}
+	return k
+}
@@ -0,0 +1,57 @@
+package tokens_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harrison-m-freitas/codectx/internal/tokens"
+)
+
+func TestCountKnownEncodings(t *testing.T) {
+	cases := []struct {
+		enc  string
+		text string
+		want int
+	}{
+		{"cl100k_base", "hello world", 2},
+		{"cl100k_base", "tiktoken is great!", 6},
+		{"o200k_base", "hello world", 2},
+		{"gpt-4", "tiktoken is great!", 6},
+	}
+	for _, c := range cases {
+		tk, err := tokens.Get(c.enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tk.Count(c.text); got != c.want {
+			t.Errorf("%s(%q)=%d, esperava %d", c.enc, c.text, got, c.want)
+		}
+	}
+}
+
+func TestUnknownTokenizer(t *testing.T) {
+	if tokens.Valid("nope") {
+		t.Fatal("nome desconhecido deveria ser inválido")
+	}
+	if _, err := tokens.Get("nope"); err == nil {
+		t.Fatal("Get deveria falhar")
+	}
+	if tk, _ := tokens.Get(""); tk.Name() != tokens.Default {
+		t.Fatalf("padrão esperado %s, got %s", tokens.Default, tk.Name())
+	}
+}
+
+func TestCountLongLine(t *testing.T) {
+	tk, _ := tokens.Get("")
+	// uma linha de 200 KB sem espaços: precisa terminar rápido e dar um
+	// número plausível
+	long := strings.Repeat("x", 200<<10)
+	n := tk.Count("antes\n" + long + "\ndepois\n")
+	if n < len(long)/16 || n > len(long) {
+		t.Fatalf("contagem implausível para linha longa: %d", n)
+	}
+	short := "func main() {\n\tfmt.Println(\"oi\")\n}\n"
+	if got, want := tk.Count(short+long), tk.Count(short)+tk.Count(long); got != want {
+		t.Fatalf("linhas curtas e longas: %d, esperava %d", got, want)
+	}
+}
@@ -10,7 +10,8 @@ import (
 	"time"
 )
 
-const noHash = "NO_HASH"
+// NoHash substitui o hash quando o arquivo não pode ser lido.
+const NoHash = "NO_HASH"
 
 func ToSlash(p string) string {
 	if p == "" {
@@ -50,30 +51,48 @@ func IsBinary(path string) (bool, error) {
 	if n <= 0 {
 		return false, nil
 	}
-	for _, b := range buf[:n] {
-		if b == 0 {
-			return true, nil
+	return HasNUL(buf[:n]), nil
+}
+
+// HasNUL aplica a heurística de IsBinary a um conteúdo já lido: byte NUL
+// nos primeiros 4 KiB.
+func HasNUL(b []byte) bool {
+	if len(b) > 4096 {
+		b = b[:4096]
+	}
+	for _, c := range b {
+		if c == 0 {
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
 func Sha256Short8(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return noHash, err
+		return NoHash, err
 	}
 	defer f.Close()
 	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
-		return noHash, err
+		return NoHash, err
 	}
-	sum := h.Sum(nil)
+	return short8(h.Sum(nil)), nil
+}
+
+// Sha256Short8Bytes é Sha256Short8 para um conteúdo já em memória.
+func Sha256Short8Bytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return short8(sum[:])
+}
+
+func short8(sum []byte) string {
 	s := hex.EncodeToString(sum)
 	if len(s) >= 8 {
-		return s[:8], nil
+		return s[:8]
 	}
-	return s, nil
+	return s
 }
 
 func OpenRead(path string) (*os.File, error)   { return os.Open(path) }